@@ -4,16 +4,17 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"path"
 	"runtime"
-	"time"
 
 	"github.com/fatih/color"
+	"github.com/rumanzo/bt2qbt/internal/deluge"
 	"github.com/rumanzo/bt2qbt/internal/options"
+	"github.com/rumanzo/bt2qbt/internal/reverse"
+	"github.com/rumanzo/bt2qbt/internal/rtorrent"
 	"github.com/rumanzo/bt2qbt/internal/transfer"
-	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/internal/transmission"
+	"github.com/rumanzo/bt2qbt/internal/utorrent"
 	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
-	"github.com/zeebo/bencode"
 )
 
 var version, commit, date, buildImage string
@@ -26,43 +27,92 @@ func main() {
 		os.Exit(0)
 	}
 
-	resumeFilePath := path.Join(opts.BitDir, "resume.dat")
-	if _, err := os.Stat(resumeFilePath); os.IsNotExist(err) {
-		log.Println("Can't find uTorrent\\Bittorrent resume file")
-		time.Sleep(30 * time.Second)
-		os.Exit(1)
+	if opts.Reverse {
+		color.Green("It will be performed export from directory %v to uTorrent resume.dat in directory %v\n", opts.QBitDir, opts.BitDir)
+		color.HiRed("Check that the uTorrent is turned off and the directory %v is backed up.\n\n", opts.BitDir)
+		options.WaitEnter(opts, "Press Enter to start")
+		log.Println("Started")
+		_, failed, err := reverse.HandleFastresumeItems(opts)
+		if err != nil {
+			log.Printf("Can't export torrents. Err: %v\n", err)
+			options.WaitEnter(opts, "\nPress Enter to exit")
+			os.Exit(options.ExitSourceUnreadable)
+		}
+		options.WaitEnter(opts, "\nPress Enter to exit")
+		if failed > 0 {
+			os.Exit(options.ExitPartial)
+		}
+		return
 	}
-	resumeFile := map[string]interface{}{}
-	err := helpers.DecodeTorrentFile(resumeFilePath, resumeFile)
-	if err != nil {
-		log.Println("Can't decode uTorrent\\Bittorrent resume file")
-		time.Sleep(30 * time.Second)
-		os.Exit(1)
-	}
-	// hate utorrent for heterogeneous resume.dat scheme
-	delete(resumeFile, ".fileguard")
-	delete(resumeFile, "rec")
-	b, _ := bencode.EncodeBytes(resumeFile)
-	resumeItems := map[string]*utorrentStructs.ResumeItem{}
-	err = bencode.DecodeBytes(b, &resumeItems)
-	if err != nil {
-		log.Printf("Can't convert resume.dat. Err: %v\n", err)
-		time.Sleep(30 * time.Second)
-		os.Exit(1)
+
+	var resumeItems map[string]*utorrentStructs.ResumeItem
+	var skipped map[string]error
+	switch opts.SourceType {
+	case options.SourceTransmission:
+		var err error
+		resumeItems, skipped, err = transmission.ReadResumeItems(opts.BitDir)
+		if err != nil {
+			log.Printf("Can't read Transmission resume files. Err: %v\n", err)
+			options.Exit(opts, options.ExitSourceUnreadable)
+		}
+	case options.SourceDeluge:
+		var err error
+		resumeItems, skipped, err = deluge.ReadResumeItems(opts.BitDir)
+		if err != nil {
+			log.Printf("Can't read Deluge state. Err: %v\n", err)
+			options.Exit(opts, options.ExitSourceUnreadable)
+		}
+	case options.SourceRTorrent:
+		var err error
+		resumeItems, skipped, err = rtorrent.ReadResumeItems(opts.BitDir)
+		if err != nil {
+			log.Printf("Can't read rTorrent session. Err: %v\n", err)
+			options.Exit(opts, options.ExitSourceUnreadable)
+		}
+	default:
+		var err error
+		resumeItems, skipped, err = utorrent.ReadResumeItems(opts.BitDir, !opts.NonInteractive)
+		if err != nil {
+			log.Printf("Can't read uTorrent\\Bittorrent resume file. Err: %v\n", err)
+			options.Exit(opts, options.ExitSourceUnreadable)
+		}
 	}
 
 	color.Green("It will be performed processing from directory %v to directory %v\n", opts.BitDir, opts.QBitDir)
-	color.HiRed("Check that the qBittorrent is turned off and the directory %v and %v is backed up.\n",
-		opts.QBitDir, opts.Categories)
-	color.HiRed("Check that you previously disable option \"Append .!ut/.!bt to incomplete files\" in preferences of uTorrent/Bittorrent \n")
-	color.HiRed("Close uTorrent/Bittorrent and qBittorrent previously\n\n")
-	fmt.Println("Press Enter to start")
-	fmt.Scanln()
+	if opts.DryRun {
+		color.Green("Dry run mode. Migration plan will be printed, nothing will be written\n\n")
+	} else if opts.OutputType == options.OutputWebui {
+		color.HiRed("Check that the qBittorrent is running with enabled WebUI\n")
+		color.HiRed("Check that you previously disable option \"Append .!ut/.!bt to incomplete files\" in preferences of uTorrent/Bittorrent \n")
+		color.HiRed("Close uTorrent/Bittorrent previously\n\n")
+		options.WaitEnter(opts, "Press Enter to start")
+	} else if opts.OutputType == options.OutputTransmission {
+		color.HiRed("Check that the Transmission is turned off and the directory %v is backed up.\n", opts.QBitDir)
+		color.HiRed("Check that you previously disable option \"Append .!ut/.!bt to incomplete files\" in preferences of uTorrent/Bittorrent \n")
+		color.HiRed("Close uTorrent/Bittorrent and Transmission previously\n\n")
+		options.WaitEnter(opts, "Press Enter to start")
+	} else {
+		color.HiRed("Check that the qBittorrent is turned off and the directory %v and %v is backed up.\n",
+			opts.QBitDir, opts.Categories)
+		color.HiRed("Check that you previously disable option \"Append .!ut/.!bt to incomplete files\" in preferences of uTorrent/Bittorrent \n")
+		color.HiRed("Close uTorrent/Bittorrent and qBittorrent previously\n\n")
+		options.WaitEnter(opts, "Press Enter to start")
+	}
 	log.Println("Started")
 
-	transfer.HandleResumeItems(opts, resumeItems)
-
-	fmt.Println("\nPress Enter to exit")
-	fmt.Scanln()
+	_, failed, err := transfer.HandleResumeItems(opts, resumeItems, skipped)
+	if err != nil {
+		log.Println(err)
+		options.Exit(opts, options.ExitSetupFailed)
+	}
+	// entries that source reader can't decode weren't migrated too
+	failed += len(skipped)
+	if len(skipped) > 0 {
+		log.Printf("%v resume entries were skipped because they can't be decoded\n", len(skipped))
+	}
 
+	options.WaitEnter(opts, "\nPress Enter to exit")
+	if failed > 0 {
+		os.Exit(options.ExitPartial)
+	}
 }
@@ -0,0 +1,208 @@
+package deluge
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rumanzo/bt2qbt/pkg/delugeStructures"
+	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/pickle"
+	"github.com/rumanzo/bt2qbt/pkg/qBittorrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/resumeHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+	"github.com/zeebo/bencode"
+)
+
+// ReadResumeItems read deluge config directory with state subdirectory and label.conf and convert every torrent
+// from torrents.state to uTorrent resume item. Keys are torrent files paths relative to directory. Torrents that
+// can't be read are skipped and returned with error by key
+func ReadResumeItems(dir string) (map[string]*utorrentStructs.ResumeItem, map[string]error, error) {
+	decoded, err := pickle.DecodeFile(filepath.Join(dir, "state", "torrents.state"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't decode torrents.state: %v", err)
+	}
+	states, err := delugeStructures.NewTorrentStates(decoded)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// deluge stores resume data of every torrent as bencoded string
+	fastresumes := map[string]string{}
+	if err = helpers.DecodeTorrentFile(filepath.Join(dir, "state", "torrents.fastresume"), &fastresumes); err != nil {
+		log.Printf("Can't decode deluge torrents.fastresume, progress and statistics will be lost. Err: %v\n", err)
+	}
+
+	labelConfig, err := ReadLabelConfig(filepath.Join(dir, "label.conf"))
+	if err != nil {
+		log.Printf("Can't read deluge label.conf, labels will be lost. Err: %v\n", err)
+	}
+
+	resumeItems := map[string]*utorrentStructs.ResumeItem{}
+	skipped := map[string]error{}
+	for _, state := range states {
+		key := fileHelpers.Join([]string{"state", state.TorrentId + ".torrent"}, `/`)
+		fastresume := &qBittorrentStructures.QBittorrentFastresume{}
+		if blob, ok := fastresumes[state.TorrentId]; ok {
+			if err = bencode.DecodeString(blob, fastresume); err != nil {
+				log.Printf("Can't decode deluge fastresume of %v. Err: %v\n", state.TorrentId, err)
+			}
+		}
+		resumeItem, err := ReadResumeItem(state, fastresume, filepath.Join(dir, key))
+		if err != nil {
+			log.Printf("Can't read deluge torrent %v. Err: %v\n", state.TorrentId, err)
+			skipped[key] = err
+			continue
+		}
+		resumeItem.Label = labelConfig.TorrentLabels[state.TorrentId]
+		resumeItems[key] = resumeItem
+	}
+	if len(resumeItems) == 0 {
+		return nil, nil, fmt.Errorf("can't find deluge torrents in %v", dir)
+	}
+	return resumeItems, skipped, nil
+}
+
+// ReadLabelConfig read label plugin config. Deluge config file contains two json objects, version and config itself
+func ReadLabelConfig(path string) (*delugeStructures.LabelConfig, error) {
+	labelConfig := &delugeStructures.LabelConfig{}
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return labelConfig, nil
+	} else if err != nil {
+		return labelConfig, err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	var objects []json.RawMessage
+	for {
+		var object json.RawMessage
+		if err = decoder.Decode(&object); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return labelConfig, err
+		}
+		objects = append(objects, object)
+	}
+	if len(objects) == 0 {
+		return labelConfig, nil
+	}
+	// old configs don't have version object
+	err = json.Unmarshal(objects[len(objects)-1], labelConfig)
+	return labelConfig, err
+}
+
+// ReadResumeItem decode torrent file and convert it with deluge state and fastresume to uTorrent resume item
+func ReadResumeItem(state delugeStructures.TorrentState, fastresume *qBittorrentStructures.QBittorrentFastresume, torrentPath string) (*utorrentStructs.ResumeItem, error) {
+	torrentFile, torrentFileRaw, err := resumeHelpers.ReadTorrentFile(torrentPath)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertResume(state, fastresume, torrentFile, torrentFileRaw)
+}
+
+// ConvertResume map deluge state and libtorrent fastresume to uTorrent resume item. State has priority over
+// fastresume, because deluge saves fastresume less often
+func ConvertResume(state delugeStructures.TorrentState, fastresume *qBittorrentStructures.QBittorrentFastresume, torrentFile *torrentStructures.Torrent, torrentFileRaw map[string]interface{}) (*utorrentStructs.ResumeItem, error) {
+	hash, err := resumeHelpers.InfoHash(torrentFile, torrentFileRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	resumeItem := &utorrentStructs.ResumeItem{
+		AddedOn:     fastresume.AddedTime,
+		CompletedOn: fastresume.CompletedTime,
+		Downloaded:  fastresume.TotalDownloaded,
+		Uploaded:    fastresume.TotalUploaded,
+		Info:        hash,
+		Runtime:     fastresume.ActiveTime,
+		Time:        fastresume.AddedTime,
+	}
+	if state.Paused {
+		resumeItem.Started = 0
+	} else if state.AutoManaged {
+		resumeItem.Started = 2
+	} else {
+		resumeItem.Started = 1
+	}
+	if state.MaxUploadSpeed > 0 {
+		resumeItem.UpSpeed = int64(state.MaxUploadSpeed * 1024)
+	}
+	if state.Name != "" && state.Name != torrentFile.GetTorrentName() {
+		resumeItem.Caption = state.Name
+	}
+
+	savePath := state.SavePath
+	if savePath == "" {
+		savePath = fastresume.SavePath
+	}
+	mappedFiles := fastresume.MappedFiles
+	fileList := resumeHelpers.FileList(torrentFile)
+	if torrentFile.IsSingle() {
+		fileName := torrentFile.GetTorrentName()
+		if len(mappedFiles) > 0 && mappedFiles[0] != "" {
+			fileName = mappedFiles[0]
+		}
+		resumeItem.Path = fileHelpers.Join([]string{savePath, fileName}, `/`)
+	} else {
+		name := torrentFile.GetTorrentName()
+		// renamed torrent directory is the first part of every mapped file
+		if len(mappedFiles) > 0 {
+			if parts := strings.SplitN(fileHelpers.Normalize(mappedFiles[0], `/`), `/`, 2); len(parts) == 2 {
+				name = parts[0]
+			}
+		}
+		resumeItem.Path = fileHelpers.Join([]string{savePath, name}, `/`)
+		resumeItem.Targets = resumeHelpers.Targets(mappedFiles, fileList)
+	}
+
+	priorities := state.FilePriorities
+	if len(priorities) == 0 {
+		priorities = fastresume.FilePriority
+	}
+	resumeItem.Prio = resumeHelpers.Priority(torrentFile, convertPriority(priorities, len(fileList)))
+
+	resumeItem.Have = resumeHelpers.HaveFromPieces(fastresume.Pieces)
+	if len(state.Trackers) > 0 {
+		resumeItem.Trackers = state.Trackers
+	} else {
+		var trackers []string
+		for _, tier := range fastresume.Trackers {
+			trackers = append(trackers, tier...)
+		}
+		if trackers != nil {
+			resumeItem.Trackers = trackers
+		}
+	}
+	return resumeItem, nil
+}
+
+// convertPriority map libtorrent priorities to uTorrent priorities. Files without priority are normal
+func convertPriority(priorities []int64, numFiles int) []byte {
+	prio := make([]byte, 0, numFiles)
+	for i := 0; i < numFiles; i++ {
+		p := int64(4)
+		if i < len(priorities) {
+			p = priorities[i]
+		}
+		switch {
+		case p <= 0:
+			prio = append(prio, 128) // don't download
+		case p < 4:
+			prio = append(prio, 4) // low
+		case p < 6:
+			prio = append(prio, 8) // normal
+		default:
+			prio = append(prio, 15) // high
+		}
+	}
+	return prio
+}
@@ -0,0 +1,104 @@
+package deluge
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/r3labs/diff/v2"
+	"github.com/rumanzo/bt2qbt/pkg/delugeStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+)
+
+func TestReadResumeItems(t *testing.T) {
+	resumeItems, _, err := ReadResumeItems("../../test/data/deluge")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := map[string]*utorrentStructs.ResumeItem{
+		"state/3456bac107634970b022677c6bfaa584065e0917.torrent": {
+			AddedOn:     1650146608,
+			CompletedOn: 1650146700,
+			Downloaded:  200,
+			Uploaded:    100,
+			Runtime:     300,
+			Time:        1650146608,
+			Label:       "movies",
+			Path:        "/home/user/Downloads/testdir",
+			Prio:        []byte{128, 4, 8, 15, 8, 8, 8, 8, 8},
+			Started:     2,
+			Targets:     [][]interface{}{{int64(0), "renamed.txt"}},
+			Have:        []byte{0x80},
+			UpSpeed:     102400,
+			Trackers:    []string{"udp://tracker.example.org:6969/announce"},
+		},
+		"state/d95d90a72e0a53e88e6f73a3905ca4fb5973472e.torrent": {
+			AddedOn:  1650146000,
+			Time:     1650146000,
+			Caption:  "renamed torrent",
+			Path:     "/home/user/Other/renamed.txt",
+			Prio:     []byte{8},
+			Started:  0,
+			Have:     []byte{0x00},
+			Trackers: []string{"udp://tracker.example.org:6969/announce"},
+		},
+	}
+	for key, resumeItem := range resumeItems {
+		// hash is taken from torrent file as is
+		if expectedItem, ok := expected[key]; ok {
+			expectedItem.Info = resumeItem.Info
+		}
+	}
+	if !reflect.DeepEqual(resumeItems, expected) {
+		changes, err := diff.Diff(resumeItems, expected, diff.DiscardComplexOrigin())
+		if err != nil {
+			t.Error(err.Error())
+		}
+		t.Fatalf("Unexpected error: structures aren't equal:\nGot: %v\nExpect %v\nDiff: %v\n", spew.Sdump(resumeItems), spew.Sdump(expected), spew.Sdump(changes))
+	}
+}
+
+func TestReadLabelConfig(t *testing.T) {
+	type ReadLabelConfigCase struct {
+		name     string
+		content  string
+		expected *delugeStructures.LabelConfig
+		mustFail bool
+	}
+	cases := []ReadLabelConfigCase{
+		{
+			name:     "001 config with version object",
+			content:  `{"file": 1, "format": 1}{"torrent_labels": {"abc": "movies"}, "labels": {"movies": {}}}`,
+			expected: &delugeStructures.LabelConfig{TorrentLabels: map[string]string{"abc": "movies"}, Labels: map[string]map[string]interface{}{"movies": {}}},
+		},
+		{
+			name:     "002 old config without version object",
+			content:  `{"torrent_labels": {"abc": "movies"}}`,
+			expected: &delugeStructures.LabelConfig{TorrentLabels: map[string]string{"abc": "movies"}},
+		},
+		{
+			name:     "003 broken config",
+			content:  `{"torrent_labels": `,
+			mustFail: true,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "label.conf")
+			if err := os.WriteFile(path, []byte(testCase.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			labelConfig, err := ReadLabelConfig(path)
+			if err != nil && !testCase.mustFail {
+				t.Fatalf("Unexpected error: %v", err)
+			} else if err == nil && testCase.mustFail {
+				t.Fatalf("Test must fail, but it doesn't")
+			}
+			if !testCase.mustFail && !reflect.DeepEqual(labelConfig, testCase.expected) {
+				t.Fatalf("Unexpected error: structures aren't equal:\nGot: %v\nExpect %v\n", spew.Sdump(labelConfig), spew.Sdump(testCase.expected))
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package options
 import (
 	"fmt"
 	"github.com/jessevdk/go-flags"
+	"github.com/rumanzo/bt2qbt/internal/report"
 	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
 	"log"
 	"os"
@@ -13,16 +14,54 @@ import (
 	"time"
 )
 
+const (
+	SourceUTorrent     = "utorrent"
+	SourceTransmission = "transmission"
+	SourceDeluge       = "deluge"
+	SourceRTorrent     = "rtorrent"
+
+	OutputFastresume   = "fastresume"
+	OutputSqlite       = "sqlite"
+	OutputWebui        = "webui"
+	OutputTransmission = "transmission"
+)
+
+// Exit codes
+const (
+	ExitOk               = 0 // all torrents migrated
+	ExitPartial          = 1 // some torrents failed
+	ExitBadOptions       = 2
+	ExitSourceUnreadable = 3
+	ExitSetupFailed      = 4 // nothing was migrated, because torrents.db, WebUI or index of search paths isn't available
+)
+
 type Opts struct {
-	BitDir        string   `short:"s" long:"source" description:"Source directory that contains resume.dat and torrents files"`
-	QBitDir       string   `short:"d" long:"destination" description:"Destination directory BT_backup (as default)"`
-	Categories    string   `short:"c" long:"categories" description:"Path to qBittorrent categories.json file (for write tags)"`
-	WithoutLabels bool     `long:"without-labels" description:"Do not export/import labels"`
-	WithoutTags   bool     `long:"without-tags" description:"Do not export/import tags"`
-	SearchPaths   []string `short:"t" long:"search" description:"Additional search path for torrents files\n	Example: --search='/mnt/olddisk/savedtorrents' --search='/mnt/olddisk/workstorrents'"`
-	Replaces      []string `short:"r" long:"replace" description:"Replace save paths. Important: you have to use single slashes in paths\n	Delimiter for from/to is comma - ,\n	Example: -r \"D:/films,/home/user/films\" -r \"D:/music,/home/user/music\"\n"`
-	PathSeparator string   `long:"sep" description:"Default path separator that will use in all paths. You may need use this flag if you migrating from windows to linux in some cases"`
-	Version       bool     `short:"v" long:"version" description:"Show version"`
+	BitDir           string   `short:"s" long:"source" description:"Source directory that contains resume.dat and torrents files"`
+	SourceType       string   `long:"source-type" choice:"utorrent" choice:"transmission" choice:"deluge" choice:"rtorrent" description:"Type of source client. For transmission source directory is config directory with resume and torrents subdirectories, for deluge it is config directory with state subdirectory and label.conf, for rtorrent it is session directory (default: utorrent)"`
+	QBitDir          string   `short:"d" long:"destination" description:"Destination directory BT_backup (as default)"`
+	OutputType       string   `long:"output-type" choice:"fastresume" choice:"sqlite" choice:"webui" choice:"transmission" description:"Type of qBittorrent resume data storage. For sqlite torrents will be inserted to torrents.db of qBittorrent 4.4+, for webui torrents will be added to running qBittorrent through WebUI API, for transmission resume and torrents subdirectories of Transmission config directory will be written to destination (default: fastresume)"`
+	TorrentsDb       string   `long:"torrents-db" description:"Path to qBittorrent torrents.db for sqlite output type (default: torrents.db near destination directory)"`
+	WebuiUrl         string   `long:"webui-url" description:"qBittorrent WebUI url for webui output type (default: http://localhost:8080)"`
+	WebuiUsername    string   `long:"webui-username" description:"qBittorrent WebUI username for webui output type"`
+	WebuiPassword    string   `long:"webui-password" description:"qBittorrent WebUI password for webui output type"`
+	Categories       string   `short:"c" long:"categories" description:"Path to qBittorrent categories.json file (for write tags)"`
+	WithoutLabels    bool     `long:"without-labels" description:"Do not export/import labels"`
+	WithoutTags      bool     `long:"without-tags" description:"Do not export/import tags"`
+	WithoutPartFiles bool     `long:"without-partfiles" description:"Do not convert uTorrent partfiles (~uTorrentPartFile_*.dat) to libtorrent partfiles"`
+	MagnetFallback   bool     `long:"magnet-fallback" description:"Import torrents without torrent file as magnet links with info hash, trackers, save path and labels from resume. qBittorrent will download metadata itself"`
+	SearchPaths      []string `short:"t" long:"search" description:"Additional search path for torrents files\n	Example: --search='/mnt/olddisk/savedtorrents' --search='/mnt/olddisk/workstorrents'"`
+	IndexSearch      bool     `long:"index-search" description:"Find torrent files by info hash in all torrent files from search paths and their subdirectories if torrent file with same name isn't found"`
+	IndexZip         bool     `long:"index-zip" description:"Find torrent files inside zip archives too with --index-search"`
+	Replaces         []string `short:"r" long:"replace" description:"Replace save paths. Important: you have to use single slashes in paths\n	Delimiter for from/to is comma - ,\n	Example: -r \"D:/films,/home/user/films\" -r \"D:/music,/home/user/music\"\n"`
+	PathSeparator    string   `long:"sep" description:"Default path separator that will use in all paths. You may need use this flag if you migrating from windows to linux in some cases"`
+	Verify           bool     `long:"verify" description:"Hash downloaded data on disk before writing fastresume. Completely verified torrents will be added in seed mode without recheck"`
+	VerifyWorkers    int      `long:"verify-workers" description:"Number of workers that hash pieces in verify mode (default: number of CPUs)"`
+	Reverse          bool     `long:"reverse" description:"Export qBittorrent fastresume and torrent files from destination directory back to uTorrent resume.dat in source directory"`
+	DryRun           bool     `long:"dry-run" description:"Only print migration plan for every torrent. Nothing will be written to destination directory and categories file. Not supported with --reverse"`
+	Report           string   `long:"report" description:"Write report with result of migration of every torrent to file. Format is chosen by extension: .json or .csv"`
+	NonInteractive   bool     `long:"non-interactive" description:"Don't wait for Enter and don't ask questions. Useful for scripts, exit code is 0 if all torrents were migrated, 1 if some torrents failed, 2 for bad options, 3 if source can't be read and 4 if destination can't be prepared"`
+	Yes              bool     `short:"y" long:"yes" description:"Same as --non-interactive"`
+	Version          bool     `short:"v" long:"version" description:"Show version"`
 }
 
 func PrepareOpts() *Opts {
@@ -58,15 +97,34 @@ func ParseOpts(opts *Opts) *Opts {
 			os.Exit(0)
 		} else {
 			log.Println(err)
-			time.Sleep(30 * time.Second)
-			os.Exit(1)
+			Exit(opts, ExitBadOptions)
 		}
 	}
 	return opts
 }
 
+// Exit wait before exit in interactive mode, so message can be read before console window will be closed
+func Exit(opts *Opts, code int) {
+	if !opts.NonInteractive && !opts.Yes {
+		time.Sleep(30 * time.Second)
+	}
+	os.Exit(code)
+}
+
+// WaitEnter wait for Enter in interactive mode
+func WaitEnter(opts *Opts, message string) {
+	if opts.NonInteractive {
+		return
+	}
+	fmt.Println(message)
+	fmt.Scanln()
+}
+
 // HandleOpts used for enrichment opts after first creation
 func HandleOpts(opts *Opts) {
+	if opts.Yes {
+		opts.NonInteractive = true
+	}
 	opts.SearchPaths = append(opts.SearchPaths, opts.BitDir)
 
 	qbtDir := fileHelpers.Normalize(opts.QBitDir, `/`)
@@ -82,6 +140,11 @@ func HandleOpts(opts *Opts) {
 }
 
 func OptsCheck(opts *Opts) error {
+	// export moves resume.dat to resume.dat.bak and writes new one, it hasn't plan to print
+	if opts.Reverse && opts.DryRun {
+		return fmt.Errorf("dry run isn't supported for export to uTorrent")
+	}
+
 	if len(opts.Replaces) != 0 {
 		for _, str := range opts.Replaces {
 			patterns := strings.Split(str, ",")
@@ -91,12 +154,21 @@ func OptsCheck(opts *Opts) error {
 		}
 	}
 
+	if opts.Report != "" {
+		if err := report.CheckPath(opts.Report); err != nil {
+			return err
+		}
+	}
+
 	if _, err := os.Stat(opts.BitDir); os.IsNotExist(err) {
 		return fmt.Errorf("can't find uTorrent\\Bittorrent folder")
 	}
 
-	if _, err := os.Stat(opts.QBitDir); os.IsNotExist(err) {
-		return fmt.Errorf("can't find qBittorrent folder")
+	// dry run doesn't write anything, so destination may not exist yet. WebUI output doesn't use destination
+	if !opts.DryRun && opts.OutputType != OutputWebui {
+		if _, err := os.Stat(opts.QBitDir); os.IsNotExist(err) {
+			return fmt.Errorf("can't find qBittorrent folder")
+		}
 	}
 
 	if runtime.GOOS == "linux" {
@@ -114,8 +186,7 @@ func MakeOpts() *Opts {
 	err := OptsCheck(opts)
 	if err != nil {
 		log.Println(err)
-		time.Sleep(time.Duration(30) * time.Second)
-		os.Exit(1)
+		Exit(opts, ExitBadOptions)
 	}
 	return opts
 }
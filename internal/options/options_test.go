@@ -72,6 +72,30 @@ func TestOptionsArgs(t *testing.T) {
 				WithoutTags:   true,
 			},
 		},
+		{
+			name: "Parse source type test",
+			args: []string{
+				"-s", "/dir",
+				"-d", "/dir",
+				"-c", "/dir/q.json",
+				"--sep", "/",
+				"--source-type", "transmission"},
+			mustFail: false,
+			expected: &Opts{
+				BitDir:        "/dir",
+				QBitDir:       "/dir",
+				Categories:    "/dir/q.json",
+				PathSeparator: "/",
+				SourceType:    SourceTransmission,
+			},
+		},
+		{
+			name: "Parse unknown source type test",
+			args: []string{
+				"--source-type", "unknown"},
+			mustFail: true,
+			expected: &Opts{},
+		},
 	}
 
 	for _, testCase := range cases {
@@ -162,6 +186,26 @@ func TestOptionsHandle(t *testing.T) {
 				PathSeparator: `\`,
 			},
 		},
+		{
+			name: "005 Yes means non-interactive",
+			opts: &Opts{
+				BitDir:        `/dir1`,
+				QBitDir:       `/dir2`,
+				Categories:    `/dir3/categories.json`,
+				PathSeparator: `/`,
+				Yes:           true,
+			},
+			mustFail: false,
+			expected: &Opts{
+				BitDir:         `/dir1`,
+				QBitDir:        `/dir2`,
+				Categories:     `/dir3/categories.json`,
+				SearchPaths:    []string{`/dir1`},
+				PathSeparator:  `/`,
+				Yes:            true,
+				NonInteractive: true,
+			},
+		},
 	}
 
 	for _, testCase := range cases {
@@ -228,6 +272,27 @@ func TestOptionsChecks(t *testing.T) {
 			},
 			mustFail: true,
 		},
+		{
+			name: "005 Must fail report with unknown format",
+			opts: &Opts{
+				BitDir:      "../../test/data",
+				QBitDir:     "../../test/data",
+				SearchPaths: []string{},
+				Report:      "report.txt",
+			},
+			mustFail: true,
+		},
+		{
+			name: "006 Must fail dry run with reverse",
+			opts: &Opts{
+				BitDir:      "../../test/data",
+				QBitDir:     "../../test/data",
+				SearchPaths: []string{},
+				Reverse:     true,
+				DryRun:      true,
+			},
+			mustFail: true,
+		},
 	}
 
 	for _, testCase := range cases {
@@ -0,0 +1,135 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type Status string
+
+const (
+	StatusOk      Status = "ok"
+	StatusSkipped Status = "skipped"
+	StatusError   Status = "error"
+)
+
+// Reason why torrent wasn't migrated
+type Reason string
+
+const (
+	ReasonLocate   Reason = "locate"   // torrent file not found
+	ReasonDecode   Reason = "decode"   // resume entry or torrent file can't be decoded
+	ReasonHash     Reason = "hash"     // info hash of torrent file doesn't match resume
+	ReasonEncode   Reason = "encode"   // resume data can't be encoded
+	ReasonPartFile Reason = "partfile" // uTorrent partfile can't be converted
+	ReasonCopy     Reason = "copy"     // output can't be written
+	ReasonPanic    Reason = "panic"
+	ReasonDryRun   Reason = "dry_run"
+)
+
+// Record result of migration of one resume item
+type Record struct {
+	Key           string   `json:"key"`
+	TorrentFile   string   `json:"torrent_file"`
+	Hash          string   `json:"hash"`
+	OutputFiles   []string `json:"output_files"`
+	SavePath      string   `json:"save_path"`
+	ContentLayout string   `json:"content_layout"`
+	Category      string   `json:"category"`
+	Tags          []string `json:"tags"`
+	State         string   `json:"state"`
+	Status        Status   `json:"status"`
+	Reason        Reason   `json:"reason,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// SetError mark record as failed
+func (record *Record) SetError(reason Reason, err error) {
+	record.Status = StatusError
+	record.Reason = reason
+	if err != nil {
+		record.Error = err.Error()
+	}
+}
+
+// Report collect records from concurrent workers
+type Report struct {
+	records []Record
+	mu      sync.Mutex
+}
+
+func New() *Report {
+	return &Report{}
+}
+
+func (report *Report) Add(record Record) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.records = append(report.records, record)
+}
+
+// Records return records sorted by key
+func (report *Report) Records() []Record {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	records := append([]Record{}, report.records...)
+	sort.Slice(records, func(i, j int) bool { return records[i].Key < records[j].Key })
+	return records
+}
+
+// CheckPath check that report format is known by extension of path
+func CheckPath(path string) error {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json", ".csv":
+		return nil
+	}
+	return fmt.Errorf("report file must have .json or .csv extension")
+}
+
+// Write write report as json or csv depending on extension of path
+func (report *Report) Write(path string) error {
+	if err := CheckPath(path); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	records := report.Records()
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		encoder := json.NewEncoder(file)
+		encoder.SetIndent("", "  ")
+		if err = encoder.Encode(records); err != nil {
+			return err
+		}
+		return file.Close()
+	}
+
+	writer := csv.NewWriter(file)
+	header := []string{"key", "torrent_file", "hash", "output_files", "save_path", "content_layout", "category", "tags",
+		"state", "status", "reason", "error"}
+	if err = writer.Write(header); err != nil {
+		return err
+	}
+	for _, record := range records {
+		row := []string{record.Key, record.TorrentFile, record.Hash, strings.Join(record.OutputFiles, ";"), record.SavePath,
+			record.ContentLayout, record.Category, strings.Join(record.Tags, ","), record.State, string(record.Status),
+			string(record.Reason), record.Error}
+		if err = writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
@@ -0,0 +1,94 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	report := New()
+	ok := Record{
+		Key:           "b.torrent",
+		TorrentFile:   "/mnt/torrents/b.torrent",
+		Hash:          "0123456789abcdef0123456789abcdef01234567",
+		OutputFiles:   []string{"/BT_backup/hash.fastresume", "/BT_backup/hash.torrent"},
+		SavePath:      "/mnt/data/",
+		ContentLayout: "Original",
+		Category:      "films",
+		Tags:          []string{"tag1", "tag2"},
+		State:         "seeding",
+		Status:        StatusOk,
+	}
+	failed := Record{Key: "a.torrent"}
+	failed.SetError(ReasonLocate, errors.New("can't locate torrent file a.torrent"))
+	report.Add(ok)
+	report.Add(failed)
+
+	type WriteCase struct {
+		name     string
+		mustFail bool
+		path     string
+	}
+	cases := []WriteCase{
+		{
+			name: "001 json",
+			path: "report.json",
+		},
+		{
+			name: "002 csv",
+			path: "report.CSV",
+		},
+		{
+			name:     "003 unknown format. mustFail",
+			path:     "report.txt",
+			mustFail: true,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), testCase.path)
+			err := report.Write(path)
+			if err != nil && !testCase.mustFail {
+				t.Fatalf("Unexpected error: %v", err)
+			} else if err == nil && testCase.mustFail {
+				t.Fatalf("Test must fail, but it doesn't")
+			}
+			if testCase.mustFail {
+				return
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			if filepath.Ext(path) == ".json" {
+				var records []Record
+				if err = json.NewDecoder(file).Decode(&records); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				if expected := []Record{failed, ok}; !reflect.DeepEqual(records, expected) {
+					t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v\n Expect %#v\n", records, expected)
+				}
+				return
+			}
+			rows, err := csv.NewReader(file).ReadAll()
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			expected := [][]string{
+				{"key", "torrent_file", "hash", "output_files", "save_path", "content_layout", "category", "tags", "state", "status", "reason", "error"},
+				{"a.torrent", "", "", "", "", "", "", "", "", "error", "locate", "can't locate torrent file a.torrent"},
+				{"b.torrent", "/mnt/torrents/b.torrent", "0123456789abcdef0123456789abcdef01234567", "/BT_backup/hash.fastresume;/BT_backup/hash.torrent",
+					"/mnt/data/", "Original", "films", "tag1,tag2", "seeding", "ok", "", ""},
+			}
+			if !reflect.DeepEqual(rows, expected) {
+				t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v\n Expect %#v\n", rows, expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package reverse
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rumanzo/bt2qbt/internal/options"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/normalization"
+)
+
+// HandleFastresumeItems export all fastresume and torrent files pairs from qBittorrent directory to uTorrent resume.dat.
+// Torrents that already exist in resume.dat are kept, old resume.dat is moved to resume.dat.bak.
+// Return numbers of exported and failed torrents
+func HandleFastresumeItems(opts *options.Opts) (exported int, failed int, err error) {
+	fastresumePaths, err := filepath.Glob(filepath.Join(opts.QBitDir, "*.fastresume"))
+	if err != nil {
+		return 0, 0, err
+	}
+	sort.Strings(fastresumePaths)
+
+	resumeFilePath := filepath.Join(opts.BitDir, "resume.dat")
+	resume := map[string]interface{}{}
+	if _, err = os.Stat(resumeFilePath); err == nil {
+		if err = helpers.DecodeTorrentFile(resumeFilePath, resume); err != nil {
+			return 0, 0, fmt.Errorf("can't decode existing resume.dat. Error: %v", err)
+		}
+		if err = os.Rename(resumeFilePath, resumeFilePath+".bak"); err != nil {
+			return 0, 0, fmt.Errorf("can't move resume.dat to resume.dat.bak. Error: %v", err)
+		}
+	}
+
+	totalJobs := len(fastresumePaths)
+	for numJob, fastresumePath := range fastresumePaths {
+		key, err := HandleFastresumeItem(opts, fastresumePath, resume)
+		if err != nil {
+			fmt.Printf("%v/%v %v \n", numJob+1, totalJobs, err)
+			failed++
+			continue
+		}
+		fmt.Printf("%v/%v Sucessfully exported %v \n", numJob+1, totalJobs, key)
+		exported++
+	}
+
+	resume[".fileguard"], err = helpers.FileGuard(resume)
+	if err != nil {
+		return 0, 0, fmt.Errorf("can't calculate resume.dat fileguard. Error: %v", err)
+	}
+	if err = helpers.WriteBencodeFile(resumeFilePath, resume); err != nil {
+		return 0, 0, fmt.Errorf("can't write resume.dat. Error: %v", err)
+	}
+
+	fmt.Println()
+	log.Println("Ended")
+	if failed > 0 {
+		log.Println("Not all torrents was processed")
+	}
+	return exported, failed, nil
+}
+
+// HandleFastresumeItem convert one fastresume file, copy torrent file to uTorrent directory and add it to resume.
+// Return key of resume item
+func HandleFastresumeItem(opts *options.Opts, fastresumePath string, resume map[string]interface{}) (string, error) {
+	reverseStruct := CreateEmptyNewReverseStructure()
+	reverseStruct.Opts = opts
+	reverseStruct.FastresumePath = fastresumePath
+	reverseStruct.TorrentFilePath = strings.TrimSuffix(fastresumePath, ".fastresume") + ".torrent"
+
+	if err := helpers.DecodeTorrentFile(reverseStruct.FastresumePath, reverseStruct.Fastresume); err != nil {
+		return "", fmt.Errorf("can't decode fastresume file %v with error %v", reverseStruct.FastresumePath, err)
+	}
+	if _, err := os.Stat(reverseStruct.TorrentFilePath); os.IsNotExist(err) {
+		return "", fmt.Errorf("can't locate torrent file %v. Magnet links without metadata can't be exported", reverseStruct.TorrentFilePath)
+	}
+	if err := helpers.DecodeTorrentFile(reverseStruct.TorrentFilePath, reverseStruct.TorrentFile); err != nil {
+		return "", fmt.Errorf("can't decode torrent file %v with error %v", reverseStruct.TorrentFilePath, err)
+	}
+
+	reverseStruct.HandleStructures()
+
+	key := reverseStruct.GetKey(resume)
+	if err := helpers.CopyFile(reverseStruct.TorrentFilePath, filepath.Join(opts.BitDir, key)); err != nil {
+		return "", fmt.Errorf("can't create uTorrent torrent file %v with error %v", filepath.Join(opts.BitDir, key), err)
+	}
+	resume[key] = reverseStruct.ResumeItem
+	return key, nil
+}
+
+// GetKey return torrent file name that doesn't exist in resume yet
+func (reverse *ReverseStructure) GetKey(resume map[string]interface{}) string {
+	name, _ := normalization.FullNormalize(reverse.TorrentFile.GetTorrentName())
+	key := name + ".torrent"
+	if existing, exists := resume[key]; exists {
+		// the same torrent exported again
+		if item, ok := existing.(map[string]interface{}); ok && item["info"] == reverse.Fastresume.InfoHash {
+			return key
+		}
+		key = name + "." + strings.ToUpper(hex.EncodeToString([]byte(reverse.Fastresume.InfoHash))) + ".torrent"
+	}
+	return key
+}
@@ -0,0 +1,58 @@
+package reverse
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rumanzo/bt2qbt/internal/options"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+)
+
+func TestHandleFastresumeItems(t *testing.T) {
+	qBitDir := t.TempDir()
+	bitDir := t.TempDir()
+	for _, name := range []string{"testdir_v1.fastresume", "testdir_v1.torrent", "testfile1_single_v1.fastresume", "testfile1_single_v1.torrent"} {
+		if err := helpers.CopyFile(filepath.Join("../../test/data", name), filepath.Join(qBitDir, name)); err != nil {
+			t.Fatalf("Can't copy test data: %v", err)
+		}
+	}
+	// fastresume without torrent file can't be exported
+	if err := helpers.CopyFile("../../test/data/testdir_v2.fastresume", filepath.Join(qBitDir, "testdir_v2.fastresume")); err != nil {
+		t.Fatalf("Can't copy test data: %v", err)
+	}
+	opts := &options.Opts{BitDir: bitDir, QBitDir: qBitDir, PathSeparator: `\`}
+	exported, failed, err := HandleFastresumeItems(opts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if exported != 2 || failed != 1 {
+		t.Fatalf("Unexpected result. Got %v exported and %v failed, expect 2 exported and 1 failed", exported, failed)
+	}
+
+	resume := map[string]interface{}{}
+	if err = helpers.DecodeTorrentFile(filepath.Join(bitDir, "resume.dat"), resume); err != nil {
+		t.Fatalf("Can't decode resume.dat: %v", err)
+	}
+	fileGuard, err := helpers.FileGuard(resume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resume[".fileguard"] != fileGuard {
+		t.Fatalf("Unexpected fileguard. Got %v, expect %v", resume[".fileguard"], fileGuard)
+	}
+
+	item := map[string]interface{}{}
+	for _, key := range []string{"testdir.torrent", "testfile1.txt.torrent"} {
+		if _, err = os.Stat(filepath.Join(bitDir, key)); err != nil {
+			t.Fatalf("Torrent file %v wasn't copied: %v", key, err)
+		}
+		var ok bool
+		if item, ok = resume[key].(map[string]interface{}); !ok {
+			t.Fatalf("Resume doesn't contain %v", key)
+		}
+	}
+	if item["path"] != `C:\Users\ruman\GolandProjects\bt2qbt\test\data\testdir\testfile1.txt` {
+		t.Fatalf("Unexpected path %v", item["path"])
+	}
+}
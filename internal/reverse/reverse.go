@@ -0,0 +1,170 @@
+package reverse
+
+import (
+	"strings"
+
+	"github.com/rumanzo/bt2qbt/internal/options"
+	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/qBittorrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/resumeHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+)
+
+// ReverseStructure used for export qBittorrent fastresume back to uTorrent resume item
+type ReverseStructure struct {
+	Fastresume      *qBittorrentStructures.QBittorrentFastresume
+	ResumeItem      *utorrentStructs.ResumeItem
+	TorrentFile     *torrentStructures.Torrent
+	Opts            *options.Opts
+	FastresumePath  string
+	TorrentFilePath string
+}
+
+func CreateEmptyNewReverseStructure() ReverseStructure {
+	return ReverseStructure{
+		Fastresume:  &qBittorrentStructures.QBittorrentFastresume{},
+		ResumeItem:  &utorrentStructs.ResumeItem{},
+		TorrentFile: &torrentStructures.Torrent{},
+		Opts:        &options.Opts{},
+	}
+}
+
+func (reverse *ReverseStructure) HandleStructures() {
+	reverse.ResumeItem.Caption = reverse.Fastresume.QbtName
+	reverse.ResumeItem.AddedOn = reverse.Fastresume.AddedTime
+	reverse.ResumeItem.CompletedOn = reverse.Fastresume.CompletedTime
+	reverse.ResumeItem.Time = reverse.Fastresume.AddedTime
+	reverse.ResumeItem.LastSeenComplete = reverse.Fastresume.LastSeenComplete
+	reverse.ResumeItem.Runtime = reverse.Fastresume.ActiveTime
+	reverse.ResumeItem.Downloaded = reverse.Fastresume.TotalDownloaded
+	reverse.ResumeItem.Uploaded = reverse.Fastresume.TotalUploaded
+	reverse.ResumeItem.Info = reverse.Fastresume.InfoHash
+	if reverse.Fastresume.UploadRateLimit > 0 {
+		reverse.ResumeItem.UpSpeed = reverse.Fastresume.UploadRateLimit
+	}
+	if !reverse.Opts.WithoutLabels {
+		reverse.ResumeItem.Label = reverse.Fastresume.QBtCategory
+	}
+	if !reverse.Opts.WithoutTags {
+		reverse.ResumeItem.Labels = reverse.Fastresume.QbtTags
+	}
+	reverse.HandleState()
+	reverse.HandleTrackers()
+	reverse.HandlePriority()
+	reverse.HandlePaths()
+	reverse.HandlePieces()
+}
+
+// HandleState reverse of transfer.HandleState. Paused torrents are stopped, not auto managed are forced
+func (reverse *ReverseStructure) HandleState() {
+	if reverse.Fastresume.Paused == 1 {
+		reverse.ResumeItem.Started = 0
+	} else if reverse.Fastresume.AutoManaged == 1 {
+		reverse.ResumeItem.Started = 2
+	} else {
+		reverse.ResumeItem.Started = 1
+	}
+}
+
+func (reverse *ReverseStructure) HandleTrackers() {
+	var trackers []interface{}
+	for _, tier := range reverse.Fastresume.Trackers {
+		for _, tracker := range tier {
+			trackers = append(trackers, tracker)
+		}
+	}
+	if trackers != nil {
+		reverse.ResumeItem.Trackers = trackers
+	}
+}
+
+// HandlePriority reverse of transfer.HandlePriority. v2 and hybrid torrents have additional byte for every file
+func (reverse *ReverseStructure) HandlePriority() {
+	prio := make([]byte, 0, len(reverse.Fastresume.FilePriority))
+	for _, p := range reverse.Fastresume.FilePriority {
+		var c byte
+		switch {
+		case p <= 0:
+			c = 128 // don't download
+		case p >= 6:
+			c = 15 // high priority
+		default:
+			c = 8 // normal priority
+		}
+		prio = append(prio, c)
+		if reverse.TorrentFile.IsV2OrHybryd() {
+			prio = append(prio, 128)
+		}
+	}
+	reverse.ResumeItem.Prio = prio
+}
+
+// HandlePaths build uTorrent path and targets. uTorrent path of multi file torrent is torrent directory,
+// path of single file torrent is full path of file. Targets are relative to path or absolute
+func (reverse *ReverseStructure) HandlePaths() {
+	separator := reverse.Opts.PathSeparator
+	savePath := reverse.Fastresume.SavePath
+	if savePath == "" {
+		savePath = reverse.Fastresume.QbtSavePath
+	}
+	torrentName, _ := reverse.TorrentFile.GetNormalizedTorrentName()
+	mappedFiles := reverse.Fastresume.MappedFiles
+
+	if reverse.TorrentFile.IsSingle() {
+		fileName := torrentName
+		if len(mappedFiles) > 0 && mappedFiles[0] != "" {
+			fileName = mappedFiles[0]
+			reverse.ResumeItem.Targets = [][]interface{}{{int64(0), fileHelpers.Normalize(fileName, separator)}}
+		}
+		if isAbs(fileName) {
+			reverse.ResumeItem.Path = fileHelpers.Normalize(fileName, separator)
+		} else {
+			reverse.ResumeItem.Path = fileHelpers.Join([]string{savePath, fileName}, separator)
+		}
+		return
+	}
+
+	fileList, _ := reverse.TorrentFile.GetFileList()
+	var prefix string
+	if reverse.Fastresume.QBtContentLayout == "NoSubfolder" {
+		reverse.ResumeItem.Path = fileHelpers.Normalize(savePath, separator)
+	} else {
+		reverse.ResumeItem.Path = fileHelpers.Join([]string{savePath, torrentName}, separator)
+		prefix = torrentName + "/"
+	}
+	for index, mappedFile := range mappedFiles {
+		if mappedFile == "" || index >= len(fileList) {
+			continue
+		}
+		var target string
+		if isAbs(mappedFile) {
+			target = fileHelpers.Normalize(mappedFile, separator)
+		} else {
+			relative := fileHelpers.Normalize(mappedFile, `/`)
+			if strings.HasPrefix(relative, prefix) {
+				relative = relative[len(prefix):]
+			} else if prefix != "" {
+				// file was moved out from torrent directory, so it can be only absolute in uTorrent
+				relative = fileHelpers.Join([]string{savePath, relative}, `/`)
+			}
+			if relative == fileList[index] {
+				continue
+			}
+			target = fileHelpers.Normalize(relative, separator)
+		}
+		reverse.ResumeItem.Targets = append(reverse.ResumeItem.Targets, []interface{}{int64(index), target})
+	}
+}
+
+// isAbs mapped files from qBittorrent on *nix systems can be absolute too
+func isAbs(filePath string) bool {
+	return fileHelpers.IsAbs(filePath) || strings.HasPrefix(filePath, "/")
+}
+
+// HandlePieces convert libtorrent pieces to uTorrent have bitfield
+func (reverse *ReverseStructure) HandlePieces() {
+	if have := resumeHelpers.HaveFromPieces(reverse.Fastresume.Pieces); have != nil {
+		reverse.ResumeItem.Have = have
+	}
+}
@@ -0,0 +1,187 @@
+package reverse
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/r3labs/diff/v2"
+	"github.com/rumanzo/bt2qbt/internal/options"
+	"github.com/rumanzo/bt2qbt/pkg/qBittorrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+)
+
+func TestReverseStructure_HandlePaths(t *testing.T) {
+	type HandlePathsCase struct {
+		name             string
+		newReverseStruct *ReverseStructure
+		expectedPath     string
+		expectedTargets  [][]interface{}
+		mustFail         bool
+	}
+	multiFileTorrent := func() *torrentStructures.Torrent {
+		return &torrentStructures.Torrent{
+			Info: &torrentStructures.TorrentInfo{
+				Name: "test_torrent",
+				Files: []*torrentStructures.TorrentFile{
+					{Path: []string{"dir1", "file1.txt"}, Length: 5},
+					{Path: []string{"file2.txt"}, Length: 5},
+				},
+			},
+		}
+	}
+	cases := []HandlePathsCase{
+		{
+			name: "001 single file",
+			newReverseStruct: &ReverseStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{SavePath: `D:\torrents\`},
+				TorrentFile: &torrentStructures.Torrent{
+					Info: &torrentStructures.TorrentInfo{Name: "test_torrent.txt"},
+				},
+				ResumeItem: &utorrentStructs.ResumeItem{},
+				Opts:       &options.Opts{PathSeparator: `\`},
+			},
+			expectedPath: `D:\torrents\test_torrent.txt`,
+		},
+		{
+			name: "002 single renamed file",
+			newReverseStruct: &ReverseStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{
+					SavePath:    `D:\torrents\`,
+					MappedFiles: []string{"renamed.txt"},
+				},
+				TorrentFile: &torrentStructures.Torrent{
+					Info: &torrentStructures.TorrentInfo{Name: "test_torrent.txt"},
+				},
+				ResumeItem: &utorrentStructs.ResumeItem{},
+				Opts:       &options.Opts{PathSeparator: `\`},
+			},
+			expectedPath:    `D:\torrents\renamed.txt`,
+			expectedTargets: [][]interface{}{{int64(0), "renamed.txt"}},
+		},
+		{
+			name: "003 multi file original with renamed files",
+			newReverseStruct: &ReverseStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{
+					SavePath:         `/mnt/torrents/`,
+					QBtContentLayout: "Original",
+					MappedFiles:      []string{"test_torrent/dir1/renamed.txt", "/mnt/other/file2.txt"},
+				},
+				TorrentFile: multiFileTorrent(),
+				ResumeItem:  &utorrentStructs.ResumeItem{},
+				Opts:        &options.Opts{PathSeparator: `/`},
+			},
+			expectedPath: `/mnt/torrents/test_torrent`,
+			expectedTargets: [][]interface{}{
+				{int64(0), "dir1/renamed.txt"},
+				{int64(1), "/mnt/other/file2.txt"},
+			},
+		},
+		{
+			name: "004 multi file nosubfolder",
+			newReverseStruct: &ReverseStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{
+					SavePath:         `/mnt/torrents/renamed_torrent`,
+					QBtContentLayout: "NoSubfolder",
+					MappedFiles:      []string{"dir1/file1.txt", "renamed.txt"},
+				},
+				TorrentFile: multiFileTorrent(),
+				ResumeItem:  &utorrentStructs.ResumeItem{},
+				Opts:        &options.Opts{PathSeparator: `/`},
+			},
+			expectedPath:    `/mnt/torrents/renamed_torrent`,
+			expectedTargets: [][]interface{}{{int64(1), "renamed.txt"}},
+		},
+		{
+			name: "005 multi file original mustfail",
+			newReverseStruct: &ReverseStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{
+					SavePath:         `/mnt/torrents/`,
+					QBtContentLayout: "Original",
+				},
+				TorrentFile: multiFileTorrent(),
+				ResumeItem:  &utorrentStructs.ResumeItem{},
+				Opts:        &options.Opts{PathSeparator: `/`},
+			},
+			expectedPath: `/mnt/torrents/`,
+			mustFail:     true,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.newReverseStruct.HandlePaths()
+			equal := testCase.newReverseStruct.ResumeItem.Path == testCase.expectedPath &&
+				reflect.DeepEqual(testCase.newReverseStruct.ResumeItem.Targets, testCase.expectedTargets)
+			if !equal && !testCase.mustFail {
+				changes, err := diff.Diff(testCase.newReverseStruct.ResumeItem.Targets, testCase.expectedTargets, diff.DiscardComplexOrigin())
+				if err != nil {
+					t.Error(err.Error())
+				}
+				t.Fatalf("Unexpected error: paths aren't equal:\nGot: %v %#v\nExpect: %v %#v\nDiff: %v\n",
+					testCase.newReverseStruct.ResumeItem.Path, testCase.newReverseStruct.ResumeItem.Targets,
+					testCase.expectedPath, testCase.expectedTargets, spew.Sdump(changes))
+			} else if equal && testCase.mustFail {
+				t.Fatalf("Unexpected error: paths are equal, but they shouldn't\nGot: %v\n", testCase.newReverseStruct.ResumeItem.Path)
+			}
+		})
+	}
+}
+
+func TestReverseStructure_HandlePriority(t *testing.T) {
+	reverseStruct := ReverseStructure{
+		Fastresume:  &qBittorrentStructures.QBittorrentFastresume{FilePriority: []int64{0, 1, 4, 6, 7}},
+		TorrentFile: &torrentStructures.Torrent{Info: &torrentStructures.TorrentInfo{}},
+		ResumeItem:  &utorrentStructs.ResumeItem{},
+	}
+	reverseStruct.HandlePriority()
+	expected := []byte{128, 8, 8, 15, 15}
+	if !reflect.DeepEqual(reverseStruct.ResumeItem.Prio, expected) {
+		t.Fatalf("Unexpected priorities:\nGot: %v\nExpect: %v", reverseStruct.ResumeItem.Prio, expected)
+	}
+
+	reverseStruct.TorrentFile.Info.FileTree = map[string]interface{}{}
+	reverseStruct.HandlePriority()
+	expected = []byte{128, 128, 8, 128, 8, 128, 15, 128, 15, 128}
+	if !reflect.DeepEqual(reverseStruct.ResumeItem.Prio, expected) {
+		t.Fatalf("Unexpected v2 priorities:\nGot: %v\nExpect: %v", reverseStruct.ResumeItem.Prio, expected)
+	}
+}
+
+func TestReverseStructure_HandleState(t *testing.T) {
+	type HandleStateCase struct {
+		name     string
+		paused   int64
+		auto     int64
+		expected int64
+	}
+	cases := []HandleStateCase{
+		{name: "001 paused", paused: 1, auto: 1, expected: 0},
+		{name: "002 started", paused: 0, auto: 1, expected: 2},
+		{name: "003 forced", paused: 0, auto: 0, expected: 1},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			reverseStruct := ReverseStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{Paused: testCase.paused, AutoManaged: testCase.auto},
+				ResumeItem: &utorrentStructs.ResumeItem{},
+			}
+			reverseStruct.HandleState()
+			if reverseStruct.ResumeItem.Started != testCase.expected {
+				t.Fatalf("Unexpected state. Got %v, expect %v", reverseStruct.ResumeItem.Started, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestReverseStructure_HandlePieces(t *testing.T) {
+	reverseStruct := ReverseStructure{
+		Fastresume: &qBittorrentStructures.QBittorrentFastresume{Pieces: []byte{1, 0, 1, 0, 0, 1, 0, 1, 1, 1}},
+		ResumeItem: &utorrentStructs.ResumeItem{},
+	}
+	reverseStruct.HandlePieces()
+	expected := []byte{0xa5, 0xc0}
+	if !reflect.DeepEqual(reverseStruct.ResumeItem.Have, expected) {
+		t.Fatalf("Unexpected have bitfield:\nGot: %x\nExpect: %x", reverseStruct.ResumeItem.Have, expected)
+	}
+}
@@ -0,0 +1,152 @@
+package rtorrent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/resumeHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/rtorrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+)
+
+// ReadResumeItems read rTorrent session directory and convert every session torrent to uTorrent resume item.
+// Keys are torrent files names relative to directory. Sessions that can't be read are skipped and returned with error
+// by key
+func ReadResumeItems(dir string) (map[string]*utorrentStructs.ResumeItem, map[string]error, error) {
+	sessionPaths, err := filepath.Glob(filepath.Join(dir, "*.torrent.rtorrent"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(sessionPaths) == 0 {
+		return nil, nil, fmt.Errorf("can't find rTorrent session files in %v", dir)
+	}
+	sort.Strings(sessionPaths)
+
+	resumeItems := map[string]*utorrentStructs.ResumeItem{}
+	skipped := map[string]error{}
+	for _, sessionPath := range sessionPaths {
+		key := strings.TrimSuffix(filepath.Base(sessionPath), ".rtorrent")
+		resumeItem, err := ReadResumeItem(filepath.Join(dir, key))
+		if err != nil {
+			log.Printf("Can't read rTorrent session %v. Err: %v\n", sessionPath, err)
+			skipped[key] = err
+			continue
+		}
+		resumeItems[key] = resumeItem
+	}
+	return resumeItems, skipped, nil
+}
+
+// ReadResumeItem decode rTorrent session torrent with its .rtorrent and .libtorrent_resume files,
+// and convert them to uTorrent resume item
+func ReadResumeItem(torrentPath string) (*utorrentStructs.ResumeItem, error) {
+	session := &rtorrentStructures.RTorrentSession{}
+	if err := helpers.DecodeTorrentFile(torrentPath+".rtorrent", session); err != nil {
+		return nil, err
+	}
+	// libtorrent_resume doesn't exist for torrents that were never started
+	resume := &rtorrentStructures.LibtorrentResume{}
+	if err := helpers.DecodeTorrentFile(torrentPath+".libtorrent_resume", resume); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Can't decode %v, progress will be lost. Err: %v\n", torrentPath+".libtorrent_resume", err)
+	}
+	torrentFile, torrentFileRaw, err := resumeHelpers.ReadTorrentFile(torrentPath)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertResume(session, resume, torrentFile, torrentFileRaw)
+}
+
+// ConvertResume map rTorrent session to uTorrent resume item. ruTorrent label and add time are taken from
+// custom session fields
+func ConvertResume(session *rtorrentStructures.RTorrentSession, resume *rtorrentStructures.LibtorrentResume, torrentFile *torrentStructures.Torrent, torrentFileRaw map[string]interface{}) (*utorrentStructs.ResumeItem, error) {
+	hash, err := resumeHelpers.InfoHash(torrentFile, torrentFileRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	resumeItem := &utorrentStructs.ResumeItem{
+		AddedOn:     session.TimestampStarted,
+		CompletedOn: session.TimestampFinished,
+		Downloaded:  session.TotalDownloaded,
+		Uploaded:    session.TotalUploaded,
+		Info:        hash,
+		Time:        session.TimestampStarted,
+	}
+	// ruTorrent store time when torrent was added
+	if addTime, err := strconv.ParseInt(strings.TrimSpace(session.Custom["addtime"]), 10, 64); err == nil {
+		resumeItem.AddedOn = addTime
+	}
+	if session.Custom1 != "" {
+		label, err := url.PathUnescape(session.Custom1)
+		if err != nil {
+			label = session.Custom1
+		}
+		resumeItem.Label = label
+	}
+	if session.State == 0 {
+		resumeItem.Started = 0
+	} else {
+		resumeItem.Started = 2
+	}
+
+	directory := session.DirectoryBase
+	if directory == "" {
+		directory = session.Directory
+	}
+	if torrentFile.IsSingle() {
+		resumeItem.Path = fileHelpers.Join([]string{directory, torrentFile.GetTorrentName()}, `/`)
+	} else {
+		resumeItem.Path = fileHelpers.Normalize(directory, `/`)
+	}
+
+	fileList := resumeHelpers.FileList(torrentFile)
+	resumeItem.Prio = resumeHelpers.Priority(torrentFile, convertPriority(resume.Files, len(fileList)))
+	resumeItem.Have = convertBitfield(resume.Bitfield, int64(len(torrentFile.Info.Pieces)/20))
+	resumeItem.Trackers = resumeHelpers.Trackers(torrentFileRaw)
+	return resumeItem, nil
+}
+
+// convertPriority map rTorrent priorities to uTorrent priorities. Files without priority are normal
+func convertPriority(files []rtorrentStructures.LibtorrentResumeFile, numFiles int) []byte {
+	prio := make([]byte, 0, numFiles)
+	for i := 0; i < numFiles; i++ {
+		p := int64(1)
+		if i < len(files) {
+			p = files[i].Priority
+		}
+		switch {
+		case p <= 0:
+			prio = append(prio, 128) // don't download
+		case p >= 2:
+			prio = append(prio, 15) // high
+		default:
+			prio = append(prio, 8) // normal
+		}
+	}
+	return prio
+}
+
+// convertBitfield return have bitfield for numPieces pieces or nil if progress is unknown.
+// rTorrent store number of pieces instead of bitfield if torrent is completed
+func convertBitfield(bitfield interface{}, numPieces int64) []byte {
+	switch value := bitfield.(type) {
+	case string:
+		return []byte(value)
+	case int64:
+		if value != numPieces {
+			return nil
+		}
+		return resumeHelpers.HaveAll(numPieces)
+	}
+	return nil
+}
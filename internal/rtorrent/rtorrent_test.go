@@ -0,0 +1,130 @@
+package rtorrent
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/r3labs/diff/v2"
+	"github.com/rumanzo/bt2qbt/pkg/rtorrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+	"github.com/zeebo/bencode"
+)
+
+func TestReadResumeItems(t *testing.T) {
+	type ReadResumeItemsCase struct {
+		name        string
+		torrentPath string
+		session     *rtorrentStructures.RTorrentSession
+		resume      *rtorrentStructures.LibtorrentResume
+		expected    *utorrentStructs.ResumeItem
+	}
+	cases := []ReadResumeItemsCase{
+		{
+			name:        "001 multi file stopped torrent with label and priorities",
+			torrentPath: "../../test/data/testdir_v1.torrent",
+			session: &rtorrentStructures.RTorrentSession{
+				Custom:            map[string]string{"addtime": "1650146600\n"},
+				Custom1:           "movies%20HD",
+				Directory:         "/home/user/Downloads/testdir",
+				DirectoryBase:     "/home/user/Downloads/testdir",
+				State:             0,
+				TimestampStarted:  1650146608,
+				TimestampFinished: 1650146700,
+				TotalDownloaded:   200,
+				TotalUploaded:     100,
+			},
+			resume: &rtorrentStructures.LibtorrentResume{
+				Bitfield: "\x00",
+				Files:    []rtorrentStructures.LibtorrentResumeFile{{Priority: 0}, {Priority: 2}, {Priority: 1}},
+			},
+			expected: &utorrentStructs.ResumeItem{
+				AddedOn:     1650146600,
+				CompletedOn: 1650146700,
+				Downloaded:  200,
+				Uploaded:    100,
+				Time:        1650146608,
+				Label:       "movies HD",
+				Path:        "/home/user/Downloads/testdir",
+				Prio:        []byte{128, 15, 8, 8, 8, 8, 8, 8, 8},
+				Started:     0,
+				Have:        []byte{0x00},
+			},
+		},
+		{
+			name:        "002 single file completed torrent",
+			torrentPath: "../../test/data/testfile1_single_v1.torrent",
+			session: &rtorrentStructures.RTorrentSession{
+				Directory: "/home/user/Downloads",
+				State:     1,
+			},
+			resume: &rtorrentStructures.LibtorrentResume{
+				Bitfield: int64(1),
+				Files:    []rtorrentStructures.LibtorrentResumeFile{{Priority: 1}},
+			},
+			expected: &utorrentStructs.ResumeItem{
+				Path:    "/home/user/Downloads/testfile1.txt",
+				Prio:    []byte{8},
+				Started: 2,
+				Have:    []byte{0x80},
+			},
+		},
+		{
+			name:        "003 torrent without libtorrent resume",
+			torrentPath: "../../test/data/testfile1_single_v1.torrent",
+			session: &rtorrentStructures.RTorrentSession{
+				Directory: "/home/user/Downloads",
+				State:     1,
+			},
+			expected: &utorrentStructs.ResumeItem{
+				Path:    "/home/user/Downloads/testfile1.txt",
+				Prio:    []byte{8},
+				Started: 2,
+			},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+			torrent, err := os.ReadFile(testCase.torrentPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key := "0123456789ABCDEF0123456789ABCDEF01234567.torrent"
+			if err = os.WriteFile(filepath.Join(dir, key), torrent, 0644); err != nil {
+				t.Fatal(err)
+			}
+			session, _ := bencode.EncodeBytes(testCase.session)
+			if err = os.WriteFile(filepath.Join(dir, key+".rtorrent"), session, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if testCase.resume != nil {
+				resume, _ := bencode.EncodeBytes(testCase.resume)
+				if err = os.WriteFile(filepath.Join(dir, key+".libtorrent_resume"), resume, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			resumeItems, _, err := ReadResumeItems(dir)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			resumeItem, ok := resumeItems[key]
+			if !ok {
+				t.Fatalf("Can't find resume item %v in %v", key, spew.Sdump(resumeItems))
+			}
+			// hash and trackers are taken from torrent file as is
+			testCase.expected.Info = resumeItem.Info
+			testCase.expected.Trackers = resumeItem.Trackers
+			if !reflect.DeepEqual(testCase.expected, resumeItem) {
+				changes, err := diff.Diff(resumeItem, testCase.expected, diff.DiscardComplexOrigin())
+				if err != nil {
+					t.Error(err.Error())
+				}
+				t.Fatalf("Unexpected error: structures aren't equal:\nGot: %#v\nExpect %#v\nDiff: %v\n", resumeItem, testCase.expected, spew.Sdump(changes))
+			}
+		})
+	}
+}
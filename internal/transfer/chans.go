@@ -1,7 +1,6 @@
 package transfer
 
 type Channels struct {
-	ComChannel     chan string
-	ErrChannel     chan string
+	Results        chan *Result
 	BoundedChannel chan bool
 }
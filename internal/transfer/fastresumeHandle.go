@@ -1,8 +1,11 @@
 package transfer
 
 import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/rumanzo/bt2qbt/pkg/helpers"
-	"time"
+	"strings"
 )
 
 func (transfer *TransferStructure) HandleStructures() {
@@ -20,18 +23,22 @@ func (transfer *TransferStructure) HandleStructures() {
 	transfer.Fastresume.CompletedTime = transfer.ResumeItem.CompletedOn
 	transfer.Fastresume.Info = transfer.TorrentFileRaw["info"]
 	transfer.Fastresume.InfoHash = transfer.ResumeItem.Info
-	transfer.Fastresume.SeedingTime = transfer.ResumeItem.Runtime
+	transfer.HandleInfoHashes()
 	transfer.HandlePriority() //  handle priorities before handling pieces and state
 	transfer.HandleState()
-	transfer.Fastresume.FinishedTime = int64(time.Since(time.Unix(transfer.ResumeItem.CompletedOn, 0)).Minutes())
+	transfer.HandleActivity()
 
 	transfer.HandleTotalDownloaded()
 	transfer.Fastresume.TotalUploaded = transfer.ResumeItem.Uploaded
-	transfer.Fastresume.UploadRateLimit = transfer.ResumeItem.UpSpeed
+	transfer.HandleLimits()
+	transfer.HandleFlags()
+	transfer.HandleSeedLimits()
 	transfer.HandleTags()
 	transfer.HandleLabels()
 
 	transfer.HandleTrackers()
+	transfer.HandlePeers()
+	transfer.HandleWebSeeds()
 
 	/*
 		pieces maps to a string whose length is a multiple of 20. It is to be subdivided into strings of length 20,
@@ -44,3 +51,23 @@ func (transfer *TransferStructure) HandleStructures() {
 	transfer.HandleSavePaths() // and there we handle torrent name also
 	transfer.HandlePieces()
 }
+
+// HandleInfoHashes fill info hashes computed from torrent file. Libtorrent store zeros instead of missing hash:
+// v1 hash of pure v2 torrents and v2 hash of v1 torrents
+func (transfer *TransferStructure) HandleInfoHashes() {
+	if transfer.Magnet || transfer.TorrentFileRaw["info"] == nil {
+		return
+	}
+	if !transfer.TorrentFile.IsV2() {
+		hash, _ := hex.DecodeString(transfer.GetHash())
+		transfer.Fastresume.InfoHash = string(hash)
+	} else {
+		transfer.Fastresume.InfoHash = strings.Repeat("\x00", sha1.Size)
+	}
+	if transfer.TorrentFile.IsV2OrHybryd() {
+		hash, _ := hex.DecodeString(transfer.GetHashV2())
+		transfer.Fastresume.InfoHash2 = string(hash)
+	} else {
+		transfer.Fastresume.InfoHash2 = strings.Repeat("\x00", sha256.Size)
+	}
+}
@@ -0,0 +1,396 @@
+package transfer
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/qBittorrentApi"
+	"github.com/rumanzo/bt2qbt/pkg/resumeHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/torrentsDb"
+	"github.com/rumanzo/bt2qbt/pkg/transmissionStructures"
+	"github.com/zeebo/bencode"
+)
+
+// Output write converted torrent to qBittorrent
+type Output interface {
+	Write(transfer *TransferStructure, hash string) error
+	// Files return files or url where torrent is written
+	Files(transfer *TransferStructure, hash string) []string
+}
+
+// FastresumeOutput write fastresume and torrent files to BT_backup directory
+type FastresumeOutput struct{}
+
+func (FastresumeOutput) Write(transfer *TransferStructure, hash string) error {
+	if err := helpers.EncodeTorrentFile(filepath.Join(transfer.Opts.QBitDir, hash+".fastresume"), transfer.Fastresume); err != nil {
+		return stageErrorf(StageEncode, "Can't create qBittorrent fastresume file %v. With error: %w", filepath.Join(transfer.Opts.QBitDir, hash+".fastresume"), err)
+	}
+	// magnet links without metadata don't have torrent file
+	if transfer.Magnet {
+		return nil
+	}
+	if err := helpers.CopyFile(transfer.TorrentFilePath, filepath.Join(transfer.Opts.QBitDir, hash+".torrent")); err != nil {
+		return stageErrorf(StageCopy, "Can't create qBittorrent torrent file %v. With error: %w", filepath.Join(transfer.Opts.QBitDir, hash+".torrent"), err)
+	}
+	return nil
+}
+
+func (FastresumeOutput) Files(transfer *TransferStructure, hash string) []string {
+	files := []string{filepath.Join(transfer.Opts.QBitDir, hash+".fastresume")}
+	if !transfer.Magnet {
+		files = append(files, filepath.Join(transfer.Opts.QBitDir, hash+".torrent"))
+	}
+	return files
+}
+
+// MagnetLink build magnet link with name and trackers of torrent
+func (transfer *TransferStructure) MagnetLink(hash string) string {
+	link := "magnet:?xt=urn:btih:" + hash
+	if transfer.Fastresume.QbtName != "" {
+		link += "&dn=" + url.QueryEscape(transfer.Fastresume.QbtName)
+	}
+	for _, tier := range transfer.Fastresume.Trackers {
+		for _, tracker := range tier {
+			link += "&tr=" + url.QueryEscape(tracker)
+		}
+	}
+	return link
+}
+
+// TorrentsDbOutput insert torrents to qBittorrent sqlite resume storage
+type TorrentsDbOutput struct {
+	Db   *torrentsDb.TorrentsDb
+	Path string
+}
+
+func (output TorrentsDbOutput) Files(transfer *TransferStructure, hash string) []string {
+	return []string{output.Path}
+}
+
+func (output TorrentsDbOutput) Write(transfer *TransferStructure, hash string) error {
+	row, err := transfer.TorrentsDbRow(hash)
+	if err != nil {
+		return newStageError(StageEncode, err)
+	}
+	if err = output.Db.Insert(row); err != nil {
+		return stageErrorf(StageCopy, "Can't insert torrent to qBittorrent torrents.db. With error: %w", err)
+	}
+	return nil
+}
+
+// TorrentsDbRow build torrents.db row. qBittorrent keeps own fields in separate columns and libtorrent resume data without them
+func (transfer *TransferStructure) TorrentsDbRow(hash string) (*torrentsDb.Row, error) {
+	resumeData := map[string]interface{}{}
+	encoded, err := bencode.EncodeBytes(transfer.Fastresume)
+	if err != nil {
+		return nil, err
+	}
+	if err = bencode.DecodeBytes(encoded, &resumeData); err != nil {
+		return nil, err
+	}
+	for key := range resumeData {
+		if strings.HasPrefix(key, "qBt-") {
+			delete(resumeData, key)
+		}
+	}
+	// torrent info stored in metadata column
+	delete(resumeData, "info")
+	libtorrentResumeData, err := bencode.EncodeBytes(resumeData)
+	if err != nil {
+		return nil, err
+	}
+	// magnet links without metadata have empty metadata
+	var metadata []byte
+	if !transfer.Magnet {
+		if metadata, err = bencode.EncodeBytes(transfer.TorrentFileRaw); err != nil {
+			return nil, err
+		}
+	}
+
+	row := &torrentsDb.Row{
+		TorrentId:              hash,
+		QueuePosition:          -1,
+		Name:                   transfer.Fastresume.QbtName,
+		Category:               transfer.Fastresume.QBtCategory,
+		Tags:                   strings.Join(transfer.Fastresume.QbtTags, ","),
+		TargetSavePath:         transfer.Fastresume.QbtSavePath,
+		ContentLayout:          transfer.Fastresume.QBtContentLayout,
+		RatioLimit:             transfer.Fastresume.QbtRatioLimit,
+		SeedingTimeLimit:       transfer.Fastresume.QbtSeedingTimeLimit,
+		HasOuterPiecesPriority: transfer.Fastresume.QBtFirstLastPiecePriority,
+		HasSeedStatus:          transfer.Fastresume.QbtSeedStatus,
+		OperatingMode:          "Forced",
+		Stopped:                transfer.Fastresume.Paused,
+		LibtorrentResumeData:   libtorrentResumeData,
+		Metadata:               metadata,
+	}
+	if transfer.Fastresume.AutoManaged == 1 {
+		row.OperatingMode = "AutoManaged"
+	}
+	return row, nil
+}
+
+// WebuiOutput add torrents to running qBittorrent through WebUI API
+type WebuiOutput struct {
+	Client *qBittorrentApi.Client
+}
+
+func (output WebuiOutput) Files(transfer *TransferStructure, hash string) []string {
+	return []string{output.Client.BaseUrl}
+}
+
+func (output WebuiOutput) Write(transfer *TransferStructure, hash string) error {
+	params := &qBittorrentApi.AddParams{
+		Savepath:      transfer.Fastresume.QbtSavePath,
+		Category:      transfer.Fastresume.QBtCategory,
+		Tags:          transfer.Fastresume.QbtTags,
+		Paused:        transfer.Fastresume.Paused == 1,
+		SkipChecking:  transfer.IsDownloaded(),
+		ContentLayout: transfer.Fastresume.QBtContentLayout,
+		Rename:        transfer.Fastresume.QbtName,
+	}
+	if transfer.Magnet {
+		if err := output.Client.AddTorrent(params, hash, nil, transfer.MagnetLink(hash)); err != nil {
+			return stageErrorf(StageCopy, "Can't add magnet %v through qBittorrent WebUI. With error: %w", hash, err)
+		}
+		return nil
+	}
+	// torrent with files that can't be moved isn't added at all
+	renames, err := transfer.WebuiRenames()
+	if err != nil {
+		return newStageError(StageCopy, err)
+	}
+	torrent, err := os.ReadFile(transfer.TorrentFilePath)
+	if err != nil {
+		return stageErrorf(StageCopy, "Can't read torrent file %v. With error: %w", transfer.TorrentFilePath, err)
+	}
+	if err = output.Client.AddTorrent(params, hash+".torrent", torrent, ""); err != nil {
+		return stageErrorf(StageCopy, "Can't add torrent %v through qBittorrent WebUI. With error: %w", transfer.TorrentFilePath, err)
+	}
+
+	// files have normal priority after adding, so only other priorities are set
+	ids := map[int64][]int{}
+	var priorities []int64
+	for index, priority := range transfer.Fastresume.FilePriority {
+		if priority == 1 {
+			continue
+		}
+		if _, ok := ids[priority]; !ok {
+			priorities = append(priorities, priority)
+		}
+		ids[priority] = append(ids[priority], index)
+	}
+	for _, priority := range priorities {
+		if err = output.Client.SetFilePriority(hash, ids[priority], priority); err != nil {
+			return stageErrorf(StageCopy, "Can't set file priorities of torrent %v through qBittorrent WebUI. With error: %w", transfer.TorrentFilePath, err)
+		}
+	}
+
+	for _, rename := range renames {
+		if err = output.Client.RenameFile(hash, rename[0], rename[1]); err != nil {
+			return stageErrorf(StageCopy, "Can't rename file %v of torrent %v through qBittorrent WebUI. With error: %w", rename[0], transfer.TorrentFilePath, err)
+		}
+	}
+	return nil
+}
+
+// IsDownloaded check that all pieces were downloaded, so qBittorrent can skip checking
+func (transfer *TransferStructure) IsDownloaded() bool {
+	if transfer.Fastresume.SeedMode == 1 {
+		return true
+	}
+	if len(transfer.Fastresume.Pieces) == 0 {
+		return false
+	}
+	for _, piece := range transfer.Fastresume.Pieces {
+		if piece&1 == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// WebuiRenames return pairs of old and new paths of renamed files. Paths are relative to save path like in WebUI.
+// Files moved to absolute paths can't be renamed through WebUI, error contains them
+func (transfer *TransferStructure) WebuiRenames() ([][2]string, error) {
+	var oldPaths []string
+	if transfer.TorrentFile.IsSingle() {
+		oldPaths = []string{transfer.TorrentFile.GetTorrentName()}
+	} else {
+		fileList, _ := transfer.TorrentFile.GetFileList()
+		for _, file := range fileList {
+			if transfer.Fastresume.QBtContentLayout == "NoSubfolder" {
+				oldPaths = append(oldPaths, file)
+			} else {
+				oldPaths = append(oldPaths, transfer.TorrentFile.GetTorrentName()+"/"+file)
+			}
+		}
+	}
+
+	var renames [][2]string
+	var absolute []string
+	for index, mappedFile := range transfer.Fastresume.MappedFiles {
+		if mappedFile == "" || index >= len(oldPaths) {
+			continue
+		}
+		if fileHelpers.IsAbs(mappedFile) || strings.HasPrefix(mappedFile, "/") {
+			absolute = append(absolute, mappedFile)
+			continue
+		}
+		newPath := fileHelpers.Normalize(mappedFile, `/`)
+		if newPath != oldPaths[index] {
+			renames = append(renames, [2]string{oldPaths[index], newPath})
+		}
+	}
+	if absolute != nil {
+		return renames, fmt.Errorf("Files with absolute paths can't be moved through qBittorrent WebUI: %v", strings.Join(absolute, ", "))
+	}
+	return renames, nil
+}
+
+// TransmissionOutput write transmission resume and torrent files to resume and torrents subdirectories of destination
+type TransmissionOutput struct{}
+
+func (TransmissionOutput) Write(transfer *TransferStructure, hash string) error {
+	// files with absolute paths can't be renamed, so nothing is written for such torrent
+	resume, err := transfer.TransmissionResume()
+	if err != nil {
+		return newStageError(StageCopy, err)
+	}
+	for _, dir := range []string{"resume", "torrents"} {
+		if err := os.MkdirAll(filepath.Join(transfer.Opts.QBitDir, dir), 0755); err != nil {
+			return stageErrorf(StageCopy, "Can't create transmission directory %v. With error: %w", filepath.Join(transfer.Opts.QBitDir, dir), err)
+		}
+	}
+	resumePath := filepath.Join(transfer.Opts.QBitDir, "resume", hash+".resume")
+	if err := helpers.WriteBencodeFile(resumePath, resume); err != nil {
+		return stageErrorf(StageEncode, "Can't create transmission resume file %v. With error: %w", resumePath, err)
+	}
+	// transmission 4 store magnet links without metadata in .magnet files
+	if transfer.Magnet {
+		magnetPath := filepath.Join(transfer.Opts.QBitDir, "torrents", hash+".magnet")
+		if err := os.WriteFile(magnetPath, []byte(transfer.MagnetLink(hash)), 0666); err != nil {
+			return stageErrorf(StageCopy, "Can't create transmission magnet file %v. With error: %w", magnetPath, err)
+		}
+	} else {
+		torrentPath := filepath.Join(transfer.Opts.QBitDir, "torrents", hash+".torrent")
+		if err := helpers.CopyFile(transfer.TorrentFilePath, torrentPath); err != nil {
+			return stageErrorf(StageCopy, "Can't create transmission torrent file %v. With error: %w", torrentPath, err)
+		}
+	}
+	return nil
+}
+
+func (TransmissionOutput) Files(transfer *TransferStructure, hash string) []string {
+	torrentFile := filepath.Join(transfer.Opts.QBitDir, "torrents", hash+".torrent")
+	if transfer.Magnet {
+		torrentFile = filepath.Join(transfer.Opts.QBitDir, "torrents", hash+".magnet")
+	}
+	return []string{filepath.Join(transfer.Opts.QBitDir, "resume", hash+".resume"), torrentFile}
+}
+
+// TransmissionResume build transmission resume from fastresume. Transmission always keeps files of multi file torrent
+// in directory named as torrent, so NoSubfolder layout becomes renamed torrent. Transmission doesn't have categories,
+// category becomes first label. Files moved to absolute paths can't be renamed in transmission, error contains them
+func (transfer *TransferStructure) TransmissionResume() (*transmissionStructures.TransmissionResume, error) {
+	resume := &transmissionStructures.TransmissionResume{
+		ActivityDate: transfer.ResumeItem.LastActive,
+		AddedDate:    transfer.Fastresume.AddedTime,
+		DoneDate:     transfer.Fastresume.CompletedTime,
+		Downloaded:   transfer.Fastresume.TotalDownloaded,
+		Paused:       transfer.Fastresume.Paused,
+		Uploaded:     transfer.Fastresume.TotalUploaded,
+	}
+	if resume.ActivityDate == 0 {
+		resume.ActivityDate = transfer.ResumeItem.Time
+	}
+	if transfer.Fastresume.QBtCategory != "" {
+		resume.Labels = append(resume.Labels, transfer.Fastresume.QBtCategory)
+	}
+	resume.Labels = append(resume.Labels, transfer.Fastresume.QbtTags...)
+
+	for _, priority := range transfer.Fastresume.FilePriority {
+		switch {
+		case priority == 0:
+			resume.Dnd = append(resume.Dnd, 1)
+			resume.Priority = append(resume.Priority, 0)
+		case priority >= 6:
+			resume.Dnd = append(resume.Dnd, 0)
+			resume.Priority = append(resume.Priority, 1)
+		default:
+			resume.Dnd = append(resume.Dnd, 0)
+			resume.Priority = append(resume.Priority, 0)
+		}
+	}
+
+	have := resumeHelpers.HaveFromPieces(transfer.Fastresume.Pieces)
+	complete := len(transfer.Fastresume.Pieces) > 0
+	for _, piece := range transfer.Fastresume.Pieces {
+		if piece&1 == 0 {
+			complete = false
+			break
+		}
+	}
+	if complete || transfer.Fastresume.SeedMode == 1 {
+		resume.Progress.Have = "all"
+	} else if len(have) > 0 {
+		resume.Progress.Bitfield = have
+	}
+
+	savePath := strings.TrimSuffix(fileHelpers.Normalize(transfer.Fastresume.QbtSavePath, `/`), `/`)
+	torrentName := transfer.TorrentFile.GetTorrentName()
+	if transfer.Magnet || transfer.TorrentFile.IsSingle() {
+		resume.Destination = savePath
+		if len(transfer.Fastresume.MappedFiles) > 0 && transfer.Fastresume.MappedFiles[0] != "" {
+			resume.Name = fileHelpers.Normalize(transfer.Fastresume.MappedFiles[0], `/`)
+		}
+		return resume, nil
+	}
+
+	name := torrentName
+	if transfer.Fastresume.QBtContentLayout == "NoSubfolder" {
+		resume.Destination = fileHelpers.CutLastPath(savePath, `/`)
+		name = fileHelpers.Base(savePath)
+		if name != torrentName {
+			resume.Name = name
+		}
+	} else {
+		resume.Destination = savePath
+	}
+	resume.Destination = strings.TrimSuffix(resume.Destination, `/`)
+
+	fileList, _ := transfer.TorrentFile.GetFileList()
+	var renamed bool
+	var absolute []string
+	files := make([]string, 0, len(fileList))
+	for index, file := range fileList {
+		path := fileHelpers.Join([]string{name, file}, `/`)
+		if index < len(transfer.Fastresume.MappedFiles) && transfer.Fastresume.MappedFiles[index] != "" {
+			mappedFile := transfer.Fastresume.MappedFiles[index]
+			if fileHelpers.IsAbs(mappedFile) || strings.HasPrefix(mappedFile, "/") {
+				absolute = append(absolute, mappedFile)
+			} else if transfer.Fastresume.QBtContentLayout == "NoSubfolder" {
+				path = fileHelpers.Join([]string{name, fileHelpers.Normalize(mappedFile, `/`)}, `/`)
+			} else {
+				// Original layout mapped files already contain torrent name
+				path = fileHelpers.Normalize(mappedFile, `/`)
+			}
+		}
+		if path != fileHelpers.Join([]string{torrentName, file}, `/`) {
+			renamed = true
+		}
+		files = append(files, path)
+	}
+	if renamed {
+		resume.Files = files
+	}
+	if absolute != nil {
+		return resume, fmt.Errorf("Files with absolute paths can't be moved in transmission: %v", strings.Join(absolute, ", "))
+	}
+	return resume, nil
+}
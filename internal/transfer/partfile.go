@@ -0,0 +1,35 @@
+package transfer
+
+import (
+	"os"
+
+	"github.com/rumanzo/bt2qbt/internal/options"
+	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/partFiles"
+)
+
+// HandlePartFile convert uTorrent partfile to libtorrent partfile in save path and mark converted pieces as downloaded.
+// Return number of converted pieces. Transmission doesn't read libtorrent partfiles, so they aren't converted for it
+func (transfer *TransferStructure) HandlePartFile(hash string) (int, error) {
+	if transfer.Opts.WithoutPartFiles || transfer.Opts.OutputType == options.OutputTransmission || transfer.Magnet || transfer.TorrentFile.IsSingle() || transfer.NumPieces == 0 {
+		return 0, nil
+	}
+	// uTorrent store partfile inside torrent directory
+	dataDir := fileHelpers.Normalize(helpers.HandleCesu8(transfer.ResumeItem.Path), string(os.PathSeparator))
+	src := partFiles.FindUTorrentPartFile(dataDir, transfer.NumPieces, transfer.TorrentFile.Info.PieceLength)
+	if src == "" {
+		return 0, nil
+	}
+	dst := fileHelpers.Join([]string{transfer.Fastresume.SavePath, partFiles.LibtorrentPartFileName(hash)}, string(os.PathSeparator))
+	pieces, err := partFiles.ConvertUTorrentPartFile(src, dst, transfer.NumPieces, transfer.TorrentFile.Info.PieceLength)
+	if err != nil {
+		return 0, err
+	}
+	if int64(len(transfer.Fastresume.Pieces)) == transfer.NumPieces {
+		for _, piece := range pieces {
+			transfer.Fastresume.Pieces[piece] = byte(1)
+		}
+	}
+	return len(pieces), nil
+}
@@ -0,0 +1,53 @@
+package transfer
+
+import (
+	"encoding/binary"
+	"net"
+)
+
+const (
+	compactPeerLen  = net.IPv4len + 2
+	compactPeer6Len = net.IPv6len + 2
+)
+
+// HandlePeers transfer peers cached by uTorrent, so torrent can connect to them before trackers and DHT respond.
+// IPv4 mapped addresses of peers6 are stored as IPv4 peers, broken and duplicated entries are skipped
+func (transfer *TransferStructure) HandlePeers() {
+	var peers, peers6 []byte
+	seen := map[string]bool{}
+	add := func(ip net.IP, port []byte) {
+		if ip.IsUnspecified() || binary.BigEndian.Uint16(port) == 0 {
+			return
+		}
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+		}
+		peer := string(ip) + string(port)
+		if seen[peer] {
+			return
+		}
+		seen[peer] = true
+		if len(ip) == net.IPv4len {
+			peers = append(peers, peer...)
+		} else {
+			peers6 = append(peers6, peer...)
+		}
+	}
+	for _, peer := range splitCompact(transfer.ResumeItem.Peers, compactPeerLen) {
+		add(net.IP(peer[:net.IPv4len]), peer[net.IPv4len:])
+	}
+	for _, peer := range splitCompact(transfer.ResumeItem.Peers6, compactPeer6Len) {
+		add(net.IP(peer[:net.IPv6len]), peer[net.IPv6len:])
+	}
+	transfer.Fastresume.Peers = string(peers)
+	transfer.Fastresume.Peers6 = string(peers6)
+}
+
+// splitCompact split compact peers to entries of given length, incomplete tail is dropped
+func splitCompact(compact string, length int) [][]byte {
+	var entries [][]byte
+	for i := 0; i+length <= len(compact); i += length {
+		entries = append(entries, []byte(compact[i:i+length]))
+	}
+	return entries
+}
@@ -0,0 +1,65 @@
+package transfer
+
+import (
+	"testing"
+
+	"github.com/rumanzo/bt2qbt/pkg/qBittorrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+)
+
+func TestTransferStructure_HandlePeers(t *testing.T) {
+	type HandlePeersCase struct {
+		name           string
+		resumeItem     *utorrentStructs.ResumeItem
+		expectedPeers  string
+		expectedPeers6 string
+	}
+	ipv6Peer := "\x20\x01\x0d\xb8\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01\x1a\xe1"
+	cases := []HandlePeersCase{
+		{
+			name:       "001 without peers",
+			resumeItem: &utorrentStructs.ResumeItem{},
+		},
+		{
+			name: "002 IPv4 and IPv6 peers",
+			resumeItem: &utorrentStructs.ResumeItem{
+				Peers:  "\xc0\xa8\x00\x01\x1a\xe1",
+				Peers6: ipv6Peer,
+			},
+			expectedPeers:  "\xc0\xa8\x00\x01\x1a\xe1",
+			expectedPeers6: ipv6Peer,
+		},
+		{
+			name: "003 IPv4 mapped peers6",
+			resumeItem: &utorrentStructs.ResumeItem{
+				Peers6: "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\xff\xff\x0a\x00\x00\x02\x1a\xe1" + ipv6Peer,
+			},
+			expectedPeers:  "\x0a\x00\x00\x02\x1a\xe1",
+			expectedPeers6: ipv6Peer,
+		},
+		{
+			name: "004 duplicated, unspecified and broken peers",
+			resumeItem: &utorrentStructs.ResumeItem{
+				Peers: "\xc0\xa8\x00\x01\x1a\xe1" + "\x00\x00\x00\x00\x1a\xe1" + "\xc0\xa8\x00\x02\x00\x00" +
+					"\xc0\xa8\x00\x01\x1a\xe1" + "\xc0\xa8",
+				Peers6: "\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\xff\xff\xc0\xa8\x00\x01\x1a\xe1",
+			},
+			expectedPeers: "\xc0\xa8\x00\x01\x1a\xe1",
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferStructure := TransferStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{},
+				ResumeItem: testCase.resumeItem,
+			}
+			transferStructure.HandlePeers()
+			if transferStructure.Fastresume.Peers != testCase.expectedPeers {
+				t.Fatalf("Unexpected peers:\n Got: %q\n Expect %q\n", transferStructure.Fastresume.Peers, testCase.expectedPeers)
+			}
+			if transferStructure.Fastresume.Peers6 != testCase.expectedPeers6 {
+				t.Fatalf("Unexpected peers6:\n Got: %q\n Expect %q\n", transferStructure.Fastresume.Peers6, testCase.expectedPeers6)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package transfer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Plan describe what will be written to qBittorrent for resume item. Used in dry run mode
+func (transfer *TransferStructure) Plan(key string, hash string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Planned import of %v\n", key)
+	fmt.Fprintf(&b, "\tInfo hash: %v\n", hash)
+	fmt.Fprintf(&b, "\tSave path: %v\n", transfer.Fastresume.QbtSavePath)
+	fmt.Fprintf(&b, "\tContent layout: %v\n", transfer.Fastresume.QBtContentLayout)
+	if len(transfer.Fastresume.MappedFiles) == 0 {
+		fmt.Fprintf(&b, "\tMapped files: none\n")
+	} else {
+		fmt.Fprintf(&b, "\tMapped files:\n")
+		for index, mappedFile := range transfer.Fastresume.MappedFiles {
+			// empty string means that file isn't renamed
+			if mappedFile != "" {
+				fmt.Fprintf(&b, "\t\t%v: %v\n", index, mappedFile)
+			}
+		}
+	}
+	fmt.Fprintf(&b, "\tCategory: %v\n", transfer.Fastresume.QBtCategory)
+	fmt.Fprintf(&b, "\tTags: %v", strings.Join(transfer.Fastresume.QbtTags, ", "))
+	return b.String()
+}
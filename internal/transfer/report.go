@@ -0,0 +1,57 @@
+package transfer
+
+import (
+	"errors"
+	"github.com/rumanzo/bt2qbt/internal/report"
+)
+
+// Record create report record from result of processing of resume item
+func (transfer *TransferStructure) Record(result *Result) report.Record {
+	record := report.Record{
+		Key:         result.Key,
+		TorrentFile: result.TorrentFile,
+		Hash:        result.Hash,
+		OutputFiles: result.OutputFiles,
+		Status:      report.StatusOk,
+	}
+	var stageErr *StageError
+	if errors.As(result.Err, &stageErr) {
+		record.SetError(report.Reason(stageErr.Stage), stageErr.Err)
+	} else if result.Skipped {
+		record.Status, record.Reason = report.StatusSkipped, report.ReasonDryRun
+	}
+	transfer.FillRecord(&record)
+	return record
+}
+
+// DecodeErrorRecord create report record of resume entry that source reader can't decode
+func DecodeErrorRecord(key string, err error) report.Record {
+	record := report.Record{Key: key}
+	record.SetError(report.ReasonDecode, err)
+	return record
+}
+
+// FillRecord fill report record with data of converted torrent. Torrents that failed before hash was computed don't
+// have converted data
+func (transfer *TransferStructure) FillRecord(record *report.Record) {
+	if record.Hash == "" {
+		return
+	}
+	record.SavePath = transfer.Fastresume.QbtSavePath
+	record.ContentLayout = transfer.Fastresume.QBtContentLayout
+	record.Category = transfer.Fastresume.QBtCategory
+	record.Tags = transfer.Fastresume.QbtTags
+	record.State = transfer.State()
+}
+
+// State return state of torrent after import: paused, downloading or seeding
+func (transfer *TransferStructure) State() string {
+	switch {
+	case transfer.Fastresume.Paused == 1:
+		return "paused"
+	case transfer.IsDownloaded():
+		return "seeding"
+	default:
+		return "downloading"
+	}
+}
@@ -0,0 +1,73 @@
+package transfer
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Stage of torrent processing
+type Stage string
+
+const (
+	StageLocate   Stage = "locate"   // search of torrent file
+	StageDecode   Stage = "decode"   // decoding of torrent file
+	StageHash     Stage = "hash"     // check of info hash
+	StageEncode   Stage = "encode"   // encoding of resume data
+	StagePartFile Stage = "partfile" // conversion of uTorrent partfile
+	StageCopy     Stage = "copy"     // writing of files to destination
+	StagePanic    Stage = "panic"
+)
+
+var (
+	ErrTorrentFileNotFound = errors.New("can't locate torrent file")
+	ErrInfoHashMismatch    = errors.New("info hash of torrent file doesn't match resume")
+)
+
+// StageError error of stage of torrent processing. Underlying error can be inspected with errors.Is and errors.As
+type StageError struct {
+	Stage Stage
+	Err   error
+}
+
+func (e *StageError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+func newStageError(stage Stage, err error) *StageError {
+	if stageErr, ok := err.(*StageError); ok {
+		return stageErr
+	}
+	return &StageError{Stage: stage, Err: err}
+}
+
+// Result of processing of one resume item. Err is *StageError if torrent wasn't imported
+type Result struct {
+	Key         string
+	TorrentFile string
+	Hash        string
+	OutputFiles []string
+	Message     string
+	Skipped     bool // torrent wasn't written in dry run mode
+	Err         error
+}
+
+func (result *Result) String() string {
+	if result.Err != nil {
+		return result.Err.Error()
+	}
+	return result.Message
+}
+
+func (result *Result) fail(stage Stage, err error) error {
+	result.Err = newStageError(stage, err)
+	return result.Err
+}
+
+// stageErrorf format error of stage, %w verb can be used for wrapping
+func stageErrorf(stage Stage, format string, a ...interface{}) *StageError {
+	return &StageError{Stage: stage, Err: fmt.Errorf(format, a...)}
+}
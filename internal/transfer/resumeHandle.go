@@ -1,12 +1,19 @@
 package transfer
 
 import (
+	"crypto/sha1"
+	"encoding/hex"
 	"fmt"
 	"github.com/rumanzo/bt2qbt/internal/options"
+	"github.com/rumanzo/bt2qbt/internal/report"
 	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
 	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/qBittorrentApi"
+	"github.com/rumanzo/bt2qbt/pkg/torrentIndex"
 	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/torrentsDb"
 	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+	"github.com/rumanzo/bt2qbt/pkg/verification"
 	"log"
 	"os"
 	"path/filepath"
@@ -16,7 +23,9 @@ import (
 	"sync"
 )
 
+// HandleResumeItem convert resume item and send result of processing to results channel. Returned error is *StageError
 func HandleResumeItem(key string, transferStruct *TransferStructure, chans *Channels, wg *sync.WaitGroup) error {
+	result := &Result{Key: key}
 
 	//panic recover
 	defer wg.Done()
@@ -25,62 +34,103 @@ func HandleResumeItem(key string, transferStruct *TransferStructure, chans *Chan
 	}()
 	defer func() {
 		if r := recover(); r != nil {
-			chans.ErrChannel <- fmt.Sprintf(
+			result.fail(StagePanic, fmt.Errorf(
 				"Panic while processing torrent %v:\n======\nReason: %v.\nText panic:\n%v\n======",
-				key, r, string(debug.Stack()))
+				key, r, string(debug.Stack())))
 		}
+		if transferStruct.Report != nil {
+			transferStruct.Report.Add(transferStruct.Record(result))
+		}
+		chans.Results <- result
 	}()
 
 	var err error
 
 	HandleTorrentFilePath(transferStruct, key)
 
-	err = FindTorrentFile(transferStruct)
-	if err != nil {
-		chans.ErrChannel <- err.Error()
-		return err
-	}
-
-	// struct for work with
-	err = helpers.DecodeTorrentFile(transferStruct.TorrentFilePath, transferStruct.TorrentFile)
-	if err != nil {
-		chans.ErrChannel <- fmt.Sprintf("Can't decode torrent file %v for torrent %v with error %v", transferStruct.TorrentFilePath, key, err)
-		return err
-	}
-
-	// because hash of info very important it will be better to use interface for get hash
 	if !strings.HasPrefix(key, "magnet:?") {
-		err = helpers.DecodeTorrentFile(transferStruct.TorrentFilePath, &transferStruct.TorrentFileRaw)
+		err = FindTorrentFile(transferStruct)
 		if err != nil {
-			chans.ErrChannel <- fmt.Sprintf("Can't decode torrent file %v for torrent %v with error %v", transferStruct.TorrentFilePath, key, err)
-			return err
+			// resume contain info hash, so torrent can be added without metadata and qBittorrent will download it
+			if !transferStruct.Opts.MagnetFallback || len(transferStruct.ResumeItem.Info) != sha1.Size {
+				return result.fail(StageLocate, err)
+			}
+			transferStruct.MissingTorrentFile = true
 		}
-	} else {
+	}
+
+	if strings.HasPrefix(key, "magnet:?") || transferStruct.MissingTorrentFile {
 		transferStruct.Magnet = true
 		transferStruct.TorrentFile = &torrentStructures.Torrent{
 			Info: &torrentStructures.TorrentInfo{},
 		}
+	} else {
+		// extracted torrent files are removed at exit, so report location inside archive
+		result.TorrentFile = transferStruct.TorrentFilePath
+		if transferStruct.TorrentFileOrigin != "" {
+			result.TorrentFile = transferStruct.TorrentFileOrigin
+		}
+		// struct for work with
+		err = helpers.DecodeTorrentFile(transferStruct.TorrentFilePath, transferStruct.TorrentFile)
+		if err != nil {
+			return result.fail(StageDecode, fmt.Errorf("Can't decode torrent file %v for torrent %v with error %w", result.TorrentFile, key, err))
+		}
+
+		// because hash of info very important it will be better to use interface for get hash
+		transferStruct.TorrentFileRaw, err = helpers.DecodeTorrentFileRaw(transferStruct.TorrentFilePath)
+		if err != nil {
+			return result.fail(StageDecode, fmt.Errorf("Can't decode torrent file %v for torrent %v with error %w", result.TorrentFile, key, err))
+		}
 	}
 
 	transferStruct.HandleStructures()
 
-	newBaseName := transferStruct.GetHash()
-	if err = helpers.EncodeTorrentFile(filepath.Join(transferStruct.Opts.QBitDir, newBaseName+".fastresume"), transferStruct.Fastresume); err != nil {
-		chans.ErrChannel <- fmt.Sprintf("Can't create qBittorrent fastresume file %v. With error: %v", filepath.Join(transferStruct.Opts.QBitDir, newBaseName+".fastresume"), err)
-		return err
+	newBaseName := transferStruct.GetTorrentId()
+	result.Hash = newBaseName
+	// torrent file found by name may be different torrent or different version of torrent
+	resumeHash := hex.EncodeToString([]byte(transferStruct.ResumeItem.Info))
+	if !transferStruct.Magnet && transferStruct.ResumeItem.Info != "" &&
+		!strings.EqualFold(resumeHash, newBaseName) && !strings.EqualFold(resumeHash, transferStruct.GetHash()) {
+		return result.fail(StageHash, fmt.Errorf("%w: info hash %v of torrent file %v, info hash %v of torrent %v",
+			ErrInfoHashMismatch, newBaseName, result.TorrentFile, resumeHash, key))
+	}
+	if transferStruct.Opts.DryRun {
+		result.Message = transferStruct.Plan(key, newBaseName)
+		result.Skipped = true
+		return nil
+	}
+	// verification read all torrent data, so it's skipped in dry run
+	transferStruct.HandleVerify()
+	partPieces, err := transferStruct.HandlePartFile(newBaseName)
+	if err != nil {
+		return result.fail(StagePartFile, fmt.Errorf("Can't convert uTorrent partfile for torrent %v with error: %w", key, err))
 	}
-	if err = helpers.CopyFile(transferStruct.TorrentFilePath, filepath.Join(transferStruct.Opts.QBitDir, newBaseName+".torrent")); err != nil {
-		chans.ErrChannel <- fmt.Sprintf("Can't create qBittorrent torrent file %v", filepath.Join(transferStruct.Opts.QBitDir, newBaseName+".torrent"))
-		return err
+	output := transferStruct.Output
+	if output == nil {
+		output = FastresumeOutput{}
 	}
-	chans.ComChannel <- fmt.Sprintf("Sucessfully imported %v", key)
+	result.OutputFiles = output.Files(transferStruct, newBaseName)
+	if err = output.Write(transferStruct, newBaseName); err != nil {
+		return result.fail(StageCopy, err)
+	}
+	if partPieces > 0 {
+		result.Message = fmt.Sprintf("Sucessfully imported %v with %v pieces from uTorrent partfile", key, partPieces)
+		return nil
+	}
+	if transferStruct.MissingTorrentFile {
+		result.Message = fmt.Sprintf("Sucessfully imported %v as magnet link without torrent file", key)
+		return nil
+	}
+	result.Message = fmt.Sprintf("Sucessfully imported %v", key)
 	return nil
 }
 
-func HandleResumeItems(opts *options.Opts, resumeItems map[string]*utorrentStructs.ResumeItem) {
+// HandleResumeItems convert all resume items and return numbers of imported and failed torrents. Skipped are entries
+// that source reader can't decode, they are only reported. Error is returned if migration can't be started at all,
+// e.g. torrents.db can't be opened
+func HandleResumeItems(opts *options.Opts, resumeItems map[string]*utorrentStructs.ResumeItem, skipped map[string]error) (imported int, failed int, err error) {
 	totalJobs := len(resumeItems)
-	chans := Channels{ComChannel: make(chan string, totalJobs),
-		ErrChannel:     make(chan string, totalJobs),
+	chans := Channels{Results: make(chan *Result, totalJobs),
 		BoundedChannel: make(chan bool, runtime.GOMAXPROCS(0)*2)}
 	numJob := 1
 	var newTags []string
@@ -90,8 +140,72 @@ func HandleResumeItems(opts *options.Opts, resumeItems map[string]*utorrentStruc
 
 	replaces := CreateReplaces(opts.Replaces)
 
+	var hasher *verification.Hasher
+	if opts.Verify && !opts.DryRun {
+		workers := opts.VerifyWorkers
+		if workers <= 0 {
+			workers = runtime.NumCPU()
+		}
+		hasher = verification.NewHasher(workers)
+		defer hasher.Close()
+	}
+
+	var migrationReport *report.Report
+	if opts.Report != "" {
+		migrationReport = report.New()
+		for key, err := range skipped {
+			migrationReport.Add(DecodeErrorRecord(helpers.HandleCesu8(key), err))
+		}
+	}
+
+	var index *torrentIndex.Index
+	if opts.IndexSearch {
+		index, err = torrentIndex.Build(opts.SearchPaths, opts.IndexZip)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Can't index torrent files in search paths. Err: %w", err)
+		}
+		defer index.Close()
+		log.Printf("Indexed %v torrent files in search paths\n", index.Len())
+	}
+
+	var output Output
+	if opts.OutputType == options.OutputSqlite && !opts.DryRun {
+		torrentsDbPath := opts.TorrentsDb
+		if torrentsDbPath == "" {
+			torrentsDbPath = filepath.Join(filepath.Dir(filepath.Clean(opts.QBitDir)), "torrents.db")
+		}
+		db, err := torrentsDb.Open(torrentsDbPath)
+		if err != nil {
+			return 0, 0, fmt.Errorf("Can't open qBittorrent torrents.db %v. Err: %w", torrentsDbPath, err)
+		}
+		defer db.Close()
+		output = TorrentsDbOutput{Db: db, Path: torrentsDbPath}
+	} else if opts.OutputType == options.OutputWebui && !opts.DryRun {
+		webuiUrl := opts.WebuiUrl
+		if webuiUrl == "" {
+			webuiUrl = "http://localhost:8080"
+		}
+		client, err := qBittorrentApi.NewClient(webuiUrl)
+		if err == nil {
+			err = client.Login(opts.WebuiUsername, opts.WebuiPassword)
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("Can't login to qBittorrent WebUI %v. Err: %w", webuiUrl, err)
+		}
+		output = WebuiOutput{Client: client}
+	} else if opts.OutputType == options.OutputTransmission && !opts.DryRun {
+		output = TransmissionOutput{}
+	}
+
+	labelCategories := opts.SourceType == options.SourceDeluge && opts.WithoutLabels == false
 	for key, resumeItem := range resumeItems {
 		positionNum++
+		// deluge labels become categories, they must be known by qBittorrent too
+		if labelCategories && resumeItem.Label != "" {
+			if exists, tag := helpers.CheckExists(helpers.HandleCesu8(resumeItem.Label), newTags); !exists {
+				newTags = append(newTags, tag)
+			}
+		}
 		if opts.WithoutTags == false {
 			if resumeItem.Labels != nil {
 				for _, label := range resumeItem.Labels {
@@ -107,24 +221,35 @@ func HandleResumeItems(opts *options.Opts, resumeItems map[string]*utorrentStruc
 		transferStruct.ResumeItem = resumeItem
 		transferStruct.Replace = replaces
 		transferStruct.Opts = opts
+		transferStruct.Hasher = hasher
+		transferStruct.Output = output
+		transferStruct.Index = index
+		transferStruct.Report = migrationReport
 		go HandleResumeItem(helpers.HandleCesu8(key), &transferStruct, &chans, &wg)
 	}
 	go func() {
 		wg.Wait()
-		close(chans.ComChannel)
-		close(chans.ErrChannel)
+		close(chans.Results)
 	}()
-	for message := range chans.ComChannel {
-		fmt.Printf("%v/%v %v \n", numJob, totalJobs, message)
+	// errors are printed after all successfully imported torrents
+	var failedResults []*Result
+	for result := range chans.Results {
+		if result.Err != nil {
+			failedResults = append(failedResults, result)
+			continue
+		}
+		fmt.Printf("%v/%v %v \n", numJob, totalJobs, result)
+		imported++
 		numJob++
 	}
-	var wasErrors bool
-	for message := range chans.ErrChannel {
-		fmt.Printf("%v/%v %v \n", numJob, totalJobs, message)
-		wasErrors = true
+	for _, result := range failedResults {
+		fmt.Printf("%v/%v %v \n", numJob, totalJobs, result)
+		failed++
 		numJob++
 	}
-	if opts.WithoutTags == false {
+	// running qBittorrent creates categories itself when torrents are added through WebUI, transmission doesn't have them
+	if (opts.WithoutTags == false || labelCategories) && !opts.DryRun &&
+		opts.OutputType != options.OutputWebui && opts.OutputType != options.OutputTransmission {
 		err := ProcessLabels(opts, newTags)
 		if err != nil {
 			fmt.Printf("Can't handle labels with error:\n%v\n", err)
@@ -132,9 +257,20 @@ func HandleResumeItems(opts *options.Opts, resumeItems map[string]*utorrentStruc
 	}
 	fmt.Println()
 	log.Println("Ended")
-	if wasErrors {
+	if opts.DryRun {
+		log.Println("It was dry run. Nothing was written")
+	}
+	if failed > 0 {
 		log.Println("Not all torrents was processed")
 	}
+	if migrationReport != nil {
+		if err := migrationReport.Write(opts.Report); err != nil {
+			log.Printf("Can't write report %v. Err: %v\n", opts.Report, err)
+		} else {
+			log.Printf("Report was written to %v\n", opts.Report)
+		}
+	}
+	return imported, failed, nil
 }
 
 // HandleTorrentFilePath check if resume key is absolute path. It means that we should search torrent file using this absolute path
@@ -160,8 +296,22 @@ func FindTorrentFile(transferStructure *TransferStructure) error {
 				return nil
 			}
 		}
+		// torrent file may be renamed or moved to subdirectory
+		if transferStructure.Index != nil && transferStructure.ResumeItem.Info != "" {
+			fullPath, err := transferStructure.Index.Find(hex.EncodeToString([]byte(transferStructure.ResumeItem.Info)))
+			if err != nil {
+				return fmt.Errorf("can't extract torrent file %v from archive: %v", transferStructure.TorrentFileName, err)
+			}
+			if fullPath != "" {
+				transferStructure.TorrentFilePath = fullPath
+				if entry, _ := transferStructure.Index.Lookup(hex.EncodeToString([]byte(transferStructure.ResumeItem.Info))); entry.Archive != "" {
+					transferStructure.TorrentFileOrigin = entry.String()
+				}
+				return nil
+			}
+		}
 		// return error only if we didn't find anything
-		return fmt.Errorf("can't locate torrent file %v", transferStructure.TorrentFileName)
+		return fmt.Errorf("%w %v", ErrTorrentFileNotFound, transferStructure.TorrentFileName)
 	}
 	return nil
 }
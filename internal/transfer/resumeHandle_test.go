@@ -1,14 +1,57 @@
 package transfer
 
 import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/r3labs/diff/v2"
 	"github.com/rumanzo/bt2qbt/internal/options"
+	"github.com/rumanzo/bt2qbt/internal/report"
 	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/partFiles"
+	"github.com/rumanzo/bt2qbt/pkg/qBittorrentApi"
+	"github.com/rumanzo/bt2qbt/pkg/qBittorrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/resumeHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/torrentIndex"
+	"github.com/rumanzo/bt2qbt/pkg/torrentsDb"
+	"github.com/rumanzo/bt2qbt/pkg/transmissionStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+	"github.com/rumanzo/bt2qbt/pkg/verification"
+	"github.com/zeebo/bencode"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 )
 
+// runResumeItem handle resume item with key like HandleResumeItems does and return result of it
+func runResumeItem(t *testing.T, transferStruct *TransferStructure, key string) *Result {
+	t.Helper()
+	chans := Channels{
+		Results:        make(chan *Result, 1),
+		BoundedChannel: make(chan bool, 1),
+	}
+	chans.BoundedChannel <- true
+	var wg sync.WaitGroup
+	wg.Add(1)
+	err := HandleResumeItem(key, transferStruct, &chans, &wg)
+	result := <-chans.Results
+	if result.Err != err {
+		t.Fatalf("Unexpected error in result: %v", result.Err)
+	}
+	return result
+}
+
 func TestSearchPaths(t *testing.T) {
 	type SearchPathCase struct {
 		name                 string
@@ -16,6 +59,11 @@ func TestSearchPaths(t *testing.T) {
 		newTransferStructure TransferStructure
 		SearchPaths          []string
 	}
+	index, err := torrentIndex.Build([]string{"../../test/data"}, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer index.Close()
 	cases := []SearchPathCase{
 		{
 			name: "001 Find relative torrent directly",
@@ -49,6 +97,27 @@ func TestSearchPaths(t *testing.T) {
 				Opts:            &options.Opts{SearchPaths: []string{"/not-exists", "../../test/data"}},
 			},
 		},
+		{
+			name: "005 Find renamed torrent by info hash",
+			newTransferStructure: TransferStructure{
+				TorrentFilePath: "",
+				TorrentFileName: "renamed.torrent",
+				Opts:            &options.Opts{SearchPaths: []string{"../../test/data"}},
+				ResumeItem:      &utorrentStructs.ResumeItem{Info: "\x34\x56\xba\xc1\x07\x63\x49\x70\xb0\x22\x67\x7c\x6b\xfa\xa5\x84\x06\x5e\x09\x17"},
+				Index:           index,
+			},
+		},
+		{
+			name:     "006 Find renamed torrent by not existing info hash. mustFail",
+			mustFail: true,
+			newTransferStructure: TransferStructure{
+				TorrentFilePath: "",
+				TorrentFileName: "renamed.torrent",
+				Opts:            &options.Opts{SearchPaths: []string{"../../test/data"}},
+				ResumeItem:      &utorrentStructs.ResumeItem{Info: strings.Repeat("\x00", 20)},
+				Index:           index,
+			},
+		},
 	}
 
 	for _, testCase := range cases {
@@ -160,3 +229,808 @@ func TestPath(t *testing.T) {
 		t.Fatalf("Can't decode torrent file with error: %v", err)
 	}
 }
+
+func TestHandleResumeItemDryRun(t *testing.T) {
+	qBitDir := t.TempDir()
+	transferStruct := CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       qBitDir,
+		PathSeparator: `/`,
+		DryRun:        true,
+	}
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path:   `/mnt/torrents/testdir`,
+		Prio:   []byte{8, 8, 8, 8, 8, 8, 8, 8, 8},
+		Label:  "films",
+		Labels: []string{"tag1", "tag2"},
+	}
+	result := runResumeItem(t, &transferStruct, "testdir_v1.torrent")
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+
+	plan := result.Message
+	for _, expected := range []string{
+		"Save path: /mnt/torrents/",
+		"Content layout: Original",
+		"Category: films",
+		"Tags: tag1, tag2",
+	} {
+		if !strings.Contains(plan, expected) {
+			t.Fatalf("Plan doesn't contain %q:\n%v", expected, plan)
+		}
+	}
+
+	entries, err := os.ReadDir(qBitDir)
+	if err != nil {
+		t.Fatalf("Can't read destination directory: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("Dry run must not write anything, but got %v files", len(entries))
+	}
+}
+
+func TestHandleResumeItemDryRunVerify(t *testing.T) {
+	// closed hasher panics on use
+	hasher := verification.NewHasher(1)
+	hasher.Close()
+	transferStruct := CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+		DryRun:        true,
+		Verify:        true,
+	}
+	transferStruct.Hasher = hasher
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path: "../../test/data/testdir",
+	}
+	result := runResumeItem(t, &transferStruct, "testdir_v1.torrent")
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+	if !result.Skipped {
+		t.Fatalf("Dry run must skip torrent")
+	}
+}
+
+func TestHandleResumeItemTorrentsDb(t *testing.T) {
+	qBitDir := t.TempDir()
+	db, err := torrentsDb.Open(filepath.Join(qBitDir, "torrents.db"))
+	if errors.Is(err, torrentsDb.ErrUnsupported) {
+		t.Skip(err)
+	} else if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer db.Close()
+
+	transferStruct := CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       qBitDir,
+		PathSeparator: `/`,
+	}
+	transferStruct.Output = TorrentsDbOutput{Db: db}
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path:   `/mnt/torrents/testdir`,
+		Prio:   []byte{8, 8, 8, 8, 8, 8, 8, 8, 8},
+		Label:  "films",
+		Labels: []string{"tag1", "tag2"},
+	}
+	if result := runResumeItem(t, &transferStruct, "testdir_v1.torrent"); result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+
+	row, err := transferStruct.TorrentsDbRow(transferStruct.GetHash())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if row.Category != "films" || row.Tags != "tag1,tag2" || row.TargetSavePath != "/mnt/torrents/" || row.ContentLayout != "Original" {
+		t.Fatalf("Unexpected row: %v", spew.Sdump(row))
+	}
+	resumeData := map[string]interface{}{}
+	if err = bencode.DecodeBytes(row.LibtorrentResumeData, &resumeData); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for key := range resumeData {
+		if strings.HasPrefix(key, "qBt-") || key == "info" {
+			t.Fatalf("Libtorrent resume data must not contain %v", key)
+		}
+	}
+
+	entries, err := os.ReadDir(qBitDir)
+	if err != nil {
+		t.Fatalf("Can't read destination directory: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("Only torrents.db must be written, but got %v files", len(entries))
+	}
+}
+
+func TestHandleResumeItemWebui(t *testing.T) {
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/v2/torrents/add":
+			r.ParseMultipartForm(1 << 20)
+			requests = append(requests, "add savepath="+r.FormValue("savepath")+" category="+r.FormValue("category")+
+				" paused="+r.FormValue("paused")+" contentLayout="+r.FormValue("contentLayout"))
+		default:
+			r.ParseForm()
+			requests = append(requests, strings.TrimPrefix(r.URL.Path, "/api/v2/torrents/")+" "+r.PostForm.Encode())
+		}
+		io.WriteString(w, "Ok.")
+	}))
+	defer server.Close()
+	client, err := qBittorrentApi.NewClient(server.URL)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	transferStruct := CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+	}
+	transferStruct.Output = WebuiOutput{Client: client}
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path:    `/mnt/torrents/testdir`,
+		Prio:    []byte{128, 8, 15, 8, 8, 8, 8, 8, 128},
+		Label:   "films",
+		Started: 2,
+		Targets: [][]interface{}{{int64(1), "renamed.txt"}},
+	}
+	if result := runResumeItem(t, &transferStruct, "testdir_v1.torrent"); result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+
+	hash := transferStruct.GetHash()
+	expected := []string{
+		"add savepath=/mnt/torrents/ category=films paused=true contentLayout=Original",
+		"filePrio hash=" + hash + "&id=0%7C8&priority=0",
+		"filePrio hash=" + hash + "&id=2&priority=6",
+		"renameFile hash=" + hash + "&newPath=testdir%2Frenamed.txt&oldPath=testdir%2Ftestfile2.txt",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("Unexpected requests:\nGot: %#v\nExpect: %#v", requests, expected)
+	}
+
+	// file moved to absolute path can't be renamed, so torrent mustn't be added
+	requests = nil
+	transferStruct = CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+	}
+	transferStruct.Output = WebuiOutput{Client: client}
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path:    `/mnt/torrents/testdir`,
+		Targets: [][]interface{}{{int64(1), `E:\other\renamed.txt`}},
+	}
+	if result := runResumeItem(t, &transferStruct, "testdir_v1.torrent"); result.Err == nil {
+		t.Fatalf("Test must fail, but it doesn't")
+	}
+	if requests != nil {
+		t.Fatalf("Unexpected requests: %#v", requests)
+	}
+}
+
+func TestHandleResumeItemTransmission(t *testing.T) {
+	transferStruct := CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+		OutputType:    options.OutputTransmission,
+	}
+	transferStruct.Output = TransmissionOutput{}
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path:        `/mnt/torrents/testdir`,
+		Prio:        []byte{128, 8, 15, 8, 8, 8, 8, 8, 128},
+		Label:       "films",
+		Labels:      []string{"tag1"},
+		Started:     0,
+		AddedOn:     1600000000,
+		CompletedOn: 1600000100,
+		Time:        1600000200,
+		Targets:     [][]interface{}{{int64(1), "renamed.txt"}},
+	}
+	if result := runResumeItem(t, &transferStruct, "testdir_v1.torrent"); result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+
+	hash := transferStruct.GetHash()
+	if _, err := os.Stat(filepath.Join(transferStruct.Opts.QBitDir, "torrents", hash+".torrent")); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	resume := &transmissionStructures.TransmissionResume{}
+	if err := helpers.DecodeTorrentFile(filepath.Join(transferStruct.Opts.QBitDir, "resume", hash+".resume"), resume); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := &transmissionStructures.TransmissionResume{
+		ActivityDate: 1600000200,
+		AddedDate:    1600000000,
+		Destination:  "/mnt/torrents",
+		Dnd:          []int64{1, 0, 0, 0, 0, 0, 0, 0, 1},
+		DoneDate:     1600000100,
+		Files: []string{
+			"testdir/testfile1.txt",
+			"testdir/renamed.txt",
+			"testdir/testfile3.txt",
+			"testdir/dir1/testfile1.txt",
+			"testdir/dir2/testfile1.txt",
+			"testdir/dir2/testfile2.txt",
+			"testdir/dir3/testfile1.txt",
+			"testdir/dir3/testfile2.txt",
+			"testdir/dir3/testfile3.txt",
+		},
+		Labels:   []string{"films", "tag1"},
+		Paused:   1,
+		Priority: []int64{0, 0, 1, 0, 0, 0, 0, 0, 0},
+		Progress: transmissionStructures.Progress{Have: "all"},
+	}
+	changes, err := diff.Diff(resume, expected, diff.DiscardComplexOrigin())
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v \n Expect %#v \n Diff: %v", resume, expected, spew.Sdump(changes))
+	}
+
+	// file moved to absolute path can't be renamed, so nothing must be written
+	transferStruct = CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+		OutputType:    options.OutputTransmission,
+	}
+	transferStruct.Output = TransmissionOutput{}
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path:    `/mnt/torrents/testdir`,
+		Targets: [][]interface{}{{int64(1), `E:\other\renamed.txt`}},
+	}
+	if result := runResumeItem(t, &transferStruct, "testdir_v1.torrent"); result.Err == nil {
+		t.Fatalf("Test must fail, but it doesn't")
+	}
+	if entries, _ := os.ReadDir(transferStruct.Opts.QBitDir); len(entries) != 0 {
+		t.Fatalf("Unexpected files in destination: %v", entries)
+	}
+}
+
+func TestHandleResumeItemInfoHashMismatch(t *testing.T) {
+	transferStruct := CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+	}
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path: `/mnt/torrents/testdir`,
+		Info: strings.Repeat("\x00", 20),
+	}
+	if result := runResumeItem(t, &transferStruct, "testdir_v1.torrent"); result.Err == nil {
+		t.Fatalf("Test must fail, but it doesn't")
+	}
+	if entries, _ := os.ReadDir(transferStruct.Opts.QBitDir); len(entries) != 0 {
+		t.Fatalf("Unexpected files in destination: %v", entries)
+	}
+}
+
+func TestHandleResumeItemResult(t *testing.T) {
+	type ResultCase struct {
+		name        string
+		mustFail    bool
+		key         string
+		info        string
+		stage       Stage
+		sentinel    error
+		outputFiles int
+		partFile    bool
+	}
+	cases := []ResultCase{
+		{
+			name:        "001 imported torrent",
+			key:         "testdir_v1.torrent",
+			outputFiles: 2,
+		},
+		{
+			name:     "002 missing torrent file",
+			mustFail: true,
+			key:      "missing.torrent",
+			stage:    StageLocate,
+			sentinel: ErrTorrentFileNotFound,
+		},
+		{
+			name:     "003 info hash mismatch",
+			mustFail: true,
+			key:      "testdir_v1.torrent",
+			info:     strings.Repeat("\x00", 20),
+			stage:    StageHash,
+			sentinel: ErrInfoHashMismatch,
+		},
+		{
+			name:     "004 partfile can't be converted",
+			mustFail: true,
+			key:      "testdir_v1.torrent",
+			partFile: true,
+			stage:    StagePartFile,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferStruct := CreateEmptyNewTransferStructure()
+			transferStruct.Opts = &options.Opts{
+				BitDir:        "../../test/data",
+				QBitDir:       t.TempDir(),
+				PathSeparator: `/`,
+			}
+			transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+				Path: `/mnt/torrents/testdir`,
+				Info: testCase.info,
+			}
+			if testCase.partFile {
+				torrentFile, torrentFileRaw, err := resumeHelpers.ReadTorrentFile(filepath.Join("../../test/data", testCase.key))
+				if err != nil {
+					t.Fatal(err)
+				}
+				hash, err := resumeHelpers.InfoHash(torrentFile, torrentFileRaw)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// directory in place of libtorrent partfile can't be replaced
+				transferStruct.ResumeItem.Path = filepath.Join(t.TempDir(), "testdir")
+				if err = os.MkdirAll(filepath.Join(filepath.Dir(transferStruct.ResumeItem.Path),
+					partFiles.LibtorrentPartFileName(hex.EncodeToString([]byte(hash))), "nested"), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.MkdirAll(transferStruct.ResumeItem.Path, 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(filepath.Join(transferStruct.ResumeItem.Path, "~uTorrentPartFile_1.dat"),
+					[]byte{1, 0, 0, 0, 1, 1, 1, 1}, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			result := runResumeItem(t, &transferStruct, testCase.key)
+			err := result.Err
+			if result.Key != testCase.key {
+				t.Fatalf("Unexpected result key %v", result.Key)
+			}
+			if err == nil && testCase.mustFail {
+				t.Fatalf("Test must fail, but it doesn't")
+			} else if err != nil && !testCase.mustFail {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(result.OutputFiles) != testCase.outputFiles {
+				t.Fatalf("Unexpected output files %v", result.OutputFiles)
+			}
+			if !testCase.mustFail {
+				return
+			}
+			var stageErr *StageError
+			if !errors.As(err, &stageErr) {
+				t.Fatalf("Error %v isn't StageError", err)
+			}
+			if stageErr.Stage != testCase.stage {
+				t.Fatalf("Unexpected stage %v, expect %v", stageErr.Stage, testCase.stage)
+			}
+			if testCase.sentinel != nil && !errors.Is(err, testCase.sentinel) {
+				t.Fatalf("Error %v doesn't wrap %v", err, testCase.sentinel)
+			}
+		})
+	}
+}
+
+func TestHandleResumeItemInfoHash2(t *testing.T) {
+	type InfoHash2Case struct {
+		name string
+		key  string
+	}
+	cases := []InfoHash2Case{
+		{
+			name: "001 v1 torrent",
+			key:  "testdir_v1",
+		},
+		{
+			name: "002 hybrid torrent",
+			key:  "testdir_hybrid",
+		},
+		{
+			name: "003 v2 torrent",
+			key:  "testdir_v2",
+		},
+		{
+			name: "004 single v2 torrent",
+			key:  "testfile1_single_v2",
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			// fastresume created by qBittorrent
+			expected := map[string]interface{}{}
+			if err := helpers.DecodeTorrentFile("../../test/data/"+testCase.key+".fastresume", &expected); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			transferStruct := CreateEmptyNewTransferStructure()
+			transferStruct.Opts = &options.Opts{
+				BitDir:        "../../test/data",
+				QBitDir:       t.TempDir(),
+				PathSeparator: `/`,
+			}
+			transferStruct.ResumeItem = &utorrentStructs.ResumeItem{Path: `/mnt/torrents/` + testCase.key}
+			if result := runResumeItem(t, &transferStruct, testCase.key+".torrent"); result.Err != nil {
+				t.Fatalf("Unexpected error: %v", result.Err)
+			}
+
+			// qBittorrent use v1 info hash or truncated v2 info hash for pure v2 torrents as name
+			id := hex.EncodeToString([]byte(expected["info-hash"].(string)))
+			if info2, ok := expected["info-hash2"].(string); ok && id == strings.Repeat("0", 40) {
+				id = hex.EncodeToString([]byte(info2))[:40]
+			}
+			fastresume := map[string]interface{}{}
+			if err := helpers.DecodeTorrentFile(filepath.Join(transferStruct.Opts.QBitDir, id+".fastresume"), &fastresume); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			for _, key := range []string{"info-hash", "info-hash2"} {
+				if fastresume[key] != expected[key] {
+					t.Fatalf("Unexpected error: opts isn't equal:\n Got: %x\n Expect %x\n", fastresume[key], expected[key])
+				}
+			}
+		})
+	}
+}
+
+func TestHandleResumeItemMagnetFallback(t *testing.T) {
+	type MagnetFallbackCase struct {
+		name     string
+		mustFail bool
+		opts     *options.Opts
+		info     string
+	}
+	cases := []MagnetFallbackCase{
+		{
+			name: "001 missing torrent file with magnet fallback",
+			opts: &options.Opts{MagnetFallback: true},
+			info: "\x01\x23\x45\x67\x89\xab\xcd\xef\x01\x23\x45\x67\x89\xab\xcd\xef\x01\x23\x45\x67",
+		},
+		{
+			name:     "002 missing torrent file without magnet fallback. mustFail",
+			opts:     &options.Opts{},
+			info:     "\x01\x23\x45\x67\x89\xab\xcd\xef\x01\x23\x45\x67\x89\xab\xcd\xef\x01\x23\x45\x67",
+			mustFail: true,
+		},
+		{
+			name:     "003 missing torrent file without info hash. mustFail",
+			opts:     &options.Opts{MagnetFallback: true},
+			mustFail: true,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferStruct := CreateEmptyNewTransferStructure()
+			transferStruct.Opts = testCase.opts
+			transferStruct.Opts.BitDir = "../../test/data"
+			transferStruct.Opts.QBitDir = t.TempDir()
+			transferStruct.Opts.PathSeparator = `/`
+			transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+				Caption:  "renamed",
+				Info:     testCase.info,
+				Label:    "films",
+				Path:     `/mnt/torrents/missing`,
+				Trackers: []interface{}{"http://tracker.org/announce"},
+			}
+			err := runResumeItem(t, &transferStruct, "missing.torrent").Err
+			if err != nil && !testCase.mustFail {
+				t.Fatalf("Unexpected error: %v", err)
+			} else if err == nil && testCase.mustFail {
+				t.Fatalf("Test must fail, but it doesn't")
+			}
+			if testCase.mustFail {
+				return
+			}
+
+			hash := "0123456789abcdef0123456789abcdef01234567"
+			if _, err = os.Stat(filepath.Join(transferStruct.Opts.QBitDir, hash+".torrent")); !os.IsNotExist(err) {
+				t.Fatalf("Magnet link must not have torrent file")
+			}
+			fastresume := &qBittorrentStructures.QBittorrentFastresume{}
+			if err = helpers.DecodeTorrentFile(filepath.Join(transferStruct.Opts.QBitDir, hash+".fastresume"), fastresume); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if fastresume.InfoHash != testCase.info || fastresume.QbtSavePath != "/mnt/torrents" || fastresume.QbtName != "renamed" ||
+				fastresume.QBtCategory != "films" || !reflect.DeepEqual(fastresume.Trackers, [][]string{{"http://tracker.org/announce"}}) {
+				t.Fatalf("Unexpected fastresume: %v", spew.Sdump(fastresume))
+			}
+		})
+	}
+}
+
+func TestHandleResumeItemArchive(t *testing.T) {
+	torrentFile, torrentFileRaw, err := resumeHelpers.ReadTorrentFile("../../test/data/testdir_v1.torrent")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	info, err := resumeHelpers.InfoHash(torrentFile, torrentFileRaw)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	data, err := os.ReadFile("../../test/data/testdir_v1.torrent")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	archivePath := filepath.Join(t.TempDir(), "archive.zip")
+	archive, err := os.Create(archivePath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	writer := zip.NewWriter(archive)
+	file, err := writer.Create("torrents/renamed.torrent")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	file.Write(data)
+	writer.Close()
+	archive.Close()
+
+	index, err := torrentIndex.Build([]string{filepath.Dir(archivePath)}, true)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	transferStruct := CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+	}
+	transferStruct.Index = index
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path: `/mnt/torrents/testdir`,
+		Info: info,
+	}
+	result := runResumeItem(t, &transferStruct, "missing.torrent")
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+	// extracted torrent file is removed, so result must point inside archive
+	if err = index.Close(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if expected := archivePath + "!torrents/renamed.torrent"; result.TorrentFile != expected {
+		t.Fatalf("Unexpected torrent file %v, expected %v", result.TorrentFile, expected)
+	}
+}
+
+func TestHandleResumeItemCopyError(t *testing.T) {
+	torrentFile, torrentFileRaw, err := resumeHelpers.ReadTorrentFile("../../test/data/testdir_v1.torrent")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hash, err := resumeHelpers.InfoHash(torrentFile, torrentFileRaw)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	type CopyErrorCase struct {
+		name       string
+		outputType string
+		output     Output
+		torrentDir string
+	}
+	cases := []CopyErrorCase{
+		{
+			name: "001 fastresume",
+		},
+		{
+			name:       "002 transmission",
+			outputType: options.OutputTransmission,
+			output:     TransmissionOutput{},
+			torrentDir: "torrents",
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferStruct := CreateEmptyNewTransferStructure()
+			transferStruct.Opts = &options.Opts{
+				BitDir:        "../../test/data",
+				QBitDir:       t.TempDir(),
+				PathSeparator: `/`,
+				OutputType:    testCase.outputType,
+			}
+			transferStruct.Output = testCase.output
+			transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+				Path: `/mnt/torrents/testdir`,
+			}
+			// directory in place of torrent file can't be replaced
+			torrentPath := filepath.Join(transferStruct.Opts.QBitDir, testCase.torrentDir, hex.EncodeToString([]byte(hash))+".torrent")
+			if err := os.MkdirAll(torrentPath, 0755); err != nil {
+				t.Fatal(err)
+			}
+			result := runResumeItem(t, &transferStruct, "testdir_v1.torrent")
+			var stageErr *StageError
+			if !errors.As(result.Err, &stageErr) || stageErr.Stage != StageCopy {
+				t.Fatalf("Unexpected error: %v", result.Err)
+			}
+			var pathErr *fs.PathError
+			if !errors.As(result.Err, &pathErr) {
+				t.Fatalf("Error %v doesn't wrap cause", result.Err)
+			}
+		})
+	}
+}
+
+func TestHandleResumeItemNonCanonical(t *testing.T) {
+	// keys of info aren't sorted and piece length has leading zero, so info hash can be kept only with raw info
+	info := "d4:name8:file.txt12:piece lengthi016384e6:pieces20:" + strings.Repeat("\x00", 20) + "6:lengthi5ee"
+	bitDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bitDir, "noncanonical.torrent"), []byte("d4:info"+info+"e"), 0644); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hash := sha1.Sum([]byte(info))
+	transferStruct := CreateEmptyNewTransferStructure()
+	transferStruct.Opts = &options.Opts{
+		BitDir:        bitDir,
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+	}
+	transferStruct.ResumeItem = &utorrentStructs.ResumeItem{
+		Path: `/mnt/torrents/file.txt`,
+		Info: string(hash[:]),
+	}
+	result := runResumeItem(t, &transferStruct, "noncanonical.torrent")
+	if result.Err != nil {
+		t.Fatalf("Unexpected error: %v", result.Err)
+	}
+	if result.Hash != hex.EncodeToString(hash[:]) {
+		t.Fatalf("Unexpected info hash %v, expected %v", result.Hash, hex.EncodeToString(hash[:]))
+	}
+	torrent, err := os.ReadFile(filepath.Join(transferStruct.Opts.QBitDir, result.Hash+".torrent"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !strings.Contains(string(torrent), info) {
+		t.Fatalf("Info of torrent file was changed: %q", torrent)
+	}
+}
+
+func TestHandleResumeItems(t *testing.T) {
+	opts := &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+		WithoutLabels: true,
+		WithoutTags:   true,
+	}
+	opts.Report = filepath.Join(opts.QBitDir, "report.json")
+	resumeItems := map[string]*utorrentStructs.ResumeItem{
+		"testdir_v1.torrent": {Path: `/mnt/torrents/testdir`},
+		"missing.torrent":    {Path: `/mnt/torrents/missing`},
+	}
+	// entries that source reader can't decode are only reported
+	skipped := map[string]error{"bad.torrent": errors.New("can't decode entry")}
+	imported, failed, err := HandleResumeItems(opts, resumeItems, skipped)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if imported != 1 || failed != 1 {
+		t.Fatalf("Unexpected result. Got %v imported and %v failed, expect 1 imported and 1 failed", imported, failed)
+	}
+
+	data, err := os.ReadFile(opts.Report)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var records []report.Record
+	if err = json.Unmarshal(data, &records); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	hash := "3456bac107634970b022677c6bfaa584065e0917"
+	expected := []report.Record{
+		{
+			Key:    "bad.torrent",
+			Status: report.StatusError,
+			Reason: report.ReasonDecode,
+			Error:  "can't decode entry",
+		},
+		{
+			Key:    "missing.torrent",
+			Status: report.StatusError,
+			Reason: report.ReasonLocate,
+			Error:  "can't locate torrent file missing.torrent",
+		},
+		{
+			Key:           "testdir_v1.torrent",
+			TorrentFile:   "../../test/data/testdir_v1.torrent",
+			Hash:          hash,
+			OutputFiles:   []string{filepath.Join(opts.QBitDir, hash+".fastresume"), filepath.Join(opts.QBitDir, hash+".torrent")},
+			SavePath:      "/mnt/torrents/",
+			ContentLayout: "Original",
+			State:         "paused",
+			Status:        report.StatusOk,
+		},
+	}
+	changes, err := diff.Diff(records, expected, diff.DiscardComplexOrigin())
+	if err != nil {
+		t.Error(err.Error())
+	}
+	if len(changes) != 0 {
+		t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v \n Expect %#v \n Diff: %v", records, expected, spew.Sdump(changes))
+	}
+}
+
+func TestHandleResumeItemsCategories(t *testing.T) {
+	type CategoriesCase struct {
+		name       string
+		sourceType string
+		expected   []string
+	}
+	cases := []CategoriesCase{
+		{
+			name:     "001 utorrent tags",
+			expected: []string{"tag1"},
+		},
+		{
+			name:       "002 deluge labels",
+			sourceType: options.SourceDeluge,
+			expected:   []string{"films", "tag1"},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			opts := &options.Opts{
+				BitDir:        "../../test/data",
+				QBitDir:       t.TempDir(),
+				PathSeparator: `/`,
+				SourceType:    testCase.sourceType,
+			}
+			opts.Categories = filepath.Join(opts.QBitDir, "categories.json")
+			resumeItems := map[string]*utorrentStructs.ResumeItem{
+				"testdir_v1.torrent": {Path: `/mnt/torrents/testdir`, Label: "films", Labels: []string{"tag1"}},
+			}
+			if _, _, err := HandleResumeItems(opts, resumeItems, nil); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+
+			data, err := os.ReadFile(opts.Categories)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			categories := map[string]interface{}{}
+			if err = json.Unmarshal(data, &categories); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			var names []string
+			for name := range categories {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if !reflect.DeepEqual(names, testCase.expected) {
+				t.Fatalf("Unexpected categories. Got %v, expect %v", names, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestHandleResumeItemsSetupFailed(t *testing.T) {
+	opts := &options.Opts{
+		BitDir:        "../../test/data",
+		QBitDir:       t.TempDir(),
+		PathSeparator: `/`,
+		OutputType:    options.OutputSqlite,
+	}
+	// torrents.db can't be created in directory that doesn't exist
+	opts.TorrentsDb = filepath.Join(opts.QBitDir, "notexists", "torrents.db")
+	resumeItems := map[string]*utorrentStructs.ResumeItem{
+		"testdir_v1.torrent": {Path: `/mnt/torrents/testdir`},
+	}
+	imported, failed, err := HandleResumeItems(opts, resumeItems, nil)
+	if err == nil {
+		t.Fatalf("Test must fail, but it doesn't")
+	}
+	if imported != 0 || failed != 0 {
+		t.Fatalf("Unexpected result. Got %v imported and %v failed, expect nothing was processed", imported, failed)
+	}
+}
@@ -2,36 +2,45 @@ package transfer
 
 import (
 	"crypto/sha1"
+	"crypto/sha256"
 	"encoding/hex"
 	"io"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/rumanzo/bt2qbt/internal/options"
 	"github.com/rumanzo/bt2qbt/internal/replace"
+	"github.com/rumanzo/bt2qbt/internal/report"
 	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
 	"github.com/rumanzo/bt2qbt/pkg/helpers"
 	"github.com/rumanzo/bt2qbt/pkg/normalization"
 	"github.com/rumanzo/bt2qbt/pkg/qBittorrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/torrentIndex"
 	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
 	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+	"github.com/rumanzo/bt2qbt/pkg/verification"
 	"github.com/zeebo/bencode"
 )
 
 //goland:noinspection GoNameStartsWithPackageName
 type TransferStructure struct {
-	Fastresume      *qBittorrentStructures.QBittorrentFastresume `bencode:"-"`
-	ResumeItem      *utorrentStructs.ResumeItem                  `bencode:"-"`
-	TorrentFile     *torrentStructures.Torrent                   `bencode:"-"`
-	TorrentFileRaw  map[string]interface{}                       `bencode:"-"`
-	Opts            *options.Opts                                `bencode:"-"`
-	TorrentFilePath string                                       `bencode:"-"`
-	TorrentFileName string                                       `bencode:"-"`
-	NumPieces       int64                                        `bencode:"-"`
-	Replace         []*replace.Replace                           `bencode:"-"`
-	Targets         map[int64]string                             `bencode:"-"`
-	Magnet          bool                                         `bencode:"-"`
+	Fastresume         *qBittorrentStructures.QBittorrentFastresume `bencode:"-"`
+	ResumeItem         *utorrentStructs.ResumeItem                  `bencode:"-"`
+	TorrentFile        *torrentStructures.Torrent                   `bencode:"-"`
+	TorrentFileRaw     map[string]interface{}                       `bencode:"-"`
+	Opts               *options.Opts                                `bencode:"-"`
+	TorrentFilePath    string                                       `bencode:"-"`
+	TorrentFileOrigin  string                                       `bencode:"-"` // archive!entry if torrent file extracted from archive
+	TorrentFileName    string                                       `bencode:"-"`
+	NumPieces          int64                                        `bencode:"-"`
+	Replace            []*replace.Replace                           `bencode:"-"`
+	Targets            map[int64]string                             `bencode:"-"`
+	Magnet             bool                                         `bencode:"-"`
+	MissingTorrentFile bool                                         `bencode:"-"` // magnet built from resume with magnet fallback
+	Hasher             *verification.Hasher                         `bencode:"-"`
+	Index              *torrentIndex.Index                          `bencode:"-"` // torrent files from search paths by info hash
+	Report             *report.Report                               `bencode:"-"`
+	Output             Output                                       `bencode:"-"` // fastresume files if nil
 }
 
 func CreateEmptyNewTransferStructure() TransferStructure {
@@ -120,14 +129,82 @@ func (transfer *TransferStructure) HandleTotalDownloaded() {
 	}
 }
 
+// HandleActivity transfer seeding time, time of last upload and download and when torrent was seen complete
+func (transfer *TransferStructure) HandleActivity() {
+	transfer.Fastresume.SeedingTime = transfer.ResumeItem.SeedTime
+	transfer.Fastresume.LastSeenComplete = transfer.ResumeItem.LastSeenComplete
+	if transfer.Fastresume.LastSeenComplete == 0 && transfer.Fastresume.CompletedTime != 0 {
+		transfer.Fastresume.LastSeenComplete = transfer.Fastresume.CompletedTime
+	}
+	// uTorrent doesn't separate uploads and downloads, completed torrents could download only before completion
+	if transfer.ResumeItem.Uploaded > 0 {
+		transfer.Fastresume.LastUpload = transfer.ResumeItem.LastActive
+	}
+	if transfer.ResumeItem.Downloaded > 0 {
+		transfer.Fastresume.LastDownload = transfer.ResumeItem.LastActive
+		if transfer.Fastresume.CompletedTime != 0 && transfer.Fastresume.CompletedTime < transfer.Fastresume.LastDownload {
+			transfer.Fastresume.LastDownload = transfer.Fastresume.CompletedTime
+		}
+	}
+}
+
+// HandleCompleted set time torrent has been active while finished, unfinished torrents are marked
 func (transfer *TransferStructure) HandleCompleted() {
 	if transfer.Fastresume.CompletedTime != 0 {
-		transfer.Fastresume.LastSeenComplete = time.Now().Unix()
+		transfer.Fastresume.FinishedTime = transfer.ResumeItem.SeedTime
 	} else {
 		transfer.Fastresume.Unfinished = new([]interface{})
 	}
 }
 
+// HandleLimits transfer speed limits and upload slots of torrent. Zero values in uTorrent mean global settings
+func (transfer *TransferStructure) HandleLimits() {
+	transfer.Fastresume.UploadRateLimit = transfer.ResumeItem.UpSpeed
+	if transfer.ResumeItem.DownSpeed > 0 {
+		transfer.Fastresume.DownloadRateLimit = transfer.ResumeItem.DownSpeed
+	}
+	if transfer.ResumeItem.UlSlots > 0 {
+		transfer.Fastresume.MaxUploads = transfer.ResumeItem.UlSlots
+	}
+}
+
+// HandleFlags transfer super seeding, sequential download and disabled DHT and PEX of torrent
+func (transfer *TransferStructure) HandleFlags() {
+	if transfer.ResumeItem.SuperSeed != 0 {
+		transfer.Fastresume.SuperSeeding = 1
+	}
+	if transfer.ResumeItem.Sequential != 0 {
+		transfer.Fastresume.SequentialDownload = 1
+	}
+	// DHT and PEX enabled if resume doesn't contain flags
+	if transfer.ResumeItem.Dht != nil && *transfer.ResumeItem.Dht == 0 {
+		transfer.Fastresume.DisableDht = 1
+	}
+	if transfer.ResumeItem.Pex != nil && *transfer.ResumeItem.Pex == 0 {
+		transfer.Fastresume.DisablePex = 1
+	}
+}
+
+// HandleSeedLimits transfer seeding goals of torrent. Torrents without own goals use global qBittorrent limits.
+// Zero goal in uTorrent means no limit
+func (transfer *TransferStructure) HandleSeedLimits() {
+	if transfer.ResumeItem.OverrideSeed == 0 {
+		return
+	}
+	// qBittorrent store ratio multiplied by 1000, so it's same as uTorrent per mille
+	if transfer.ResumeItem.WantedRatio > 0 {
+		transfer.Fastresume.QbtRatioLimit = transfer.ResumeItem.WantedRatio
+	} else {
+		transfer.Fastresume.QbtRatioLimit = -1000
+	}
+	// qBittorrent store seeding time in minutes
+	if transfer.ResumeItem.WantedSeedtime > 0 {
+		transfer.Fastresume.QbtSeedingTimeLimit = (transfer.ResumeItem.WantedSeedtime + 59) / 60
+	} else {
+		transfer.Fastresume.QbtSeedingTimeLimit = -1
+	}
+}
+
 func (transfer *TransferStructure) HandleTags() {
 	if transfer.Opts.WithoutTags == false && transfer.ResumeItem.Labels != nil {
 		for _, label := range transfer.ResumeItem.Labels {
@@ -173,6 +250,25 @@ func (transfer *TransferStructure) HandleTrackers() {
 	}
 }
 
+// HandleWebSeeds merge web seeds and http seeds of torrent file with seeds added in uTorrent
+func (transfer *TransferStructure) HandleWebSeeds() {
+	transfer.Fastresume.UrlList = mergeSeeds(transfer.TorrentFile.UrlList, transfer.ResumeItem.UrlList)
+	transfer.Fastresume.HttpSeeds = mergeSeeds(transfer.TorrentFile.HttpSeeds, transfer.ResumeItem.HttpSeeds)
+}
+
+// mergeSeeds return unique seeds in order of appearance
+func mergeSeeds(seedLists ...interface{}) []string {
+	var seeds []string
+	for _, seedList := range seedLists {
+		for _, seed := range helpers.GetStrings(seedList) {
+			if exists, _ := helpers.CheckExists(seed, seeds); !exists {
+				seeds = append(seeds, seed)
+			}
+		}
+	}
+	return seeds
+}
+
 func (transfer *TransferStructure) HandlePriority() {
 	if transfer.TorrentFile.IsV2OrHybryd() { // so we need get only odd
 		trimmedPrio := make([]byte, 0, len(transfer.ResumeItem.Prio)/2)
@@ -194,7 +290,12 @@ func (transfer *TransferStructure) HandlePriority() {
 	}
 }
 
+// GetHash return SHA-1 info hash. Info of torrent file read by DecodeTorrentFileRaw is raw bytes, so they are hashed as is.
+// Magnet links don't have info, hash from resume is used for them
 func (transfer *TransferStructure) GetHash() (hash string) {
+	if transfer.Magnet && transfer.ResumeItem.Info != "" {
+		return hex.EncodeToString([]byte(transfer.ResumeItem.Info))
+	}
 	torinfo, _ := bencode.EncodeString(transfer.TorrentFileRaw["info"])
 	h := sha1.New()
 	io.WriteString(h, torinfo)
@@ -202,7 +303,30 @@ func (transfer *TransferStructure) GetHash() (hash string) {
 	return
 }
 
+// GetTorrentId return id that qBittorrent uses as name of fastresume. It's SHA-1 info hash for v1 and hybrid torrents and
+// truncated to 20 bytes SHA-256 info hash for pure v2 torrents
+func (transfer *TransferStructure) GetTorrentId() string {
+	if !transfer.Magnet && transfer.TorrentFile.IsV2() {
+		return transfer.GetHashV2()[:40]
+	}
+	return transfer.GetHash()
+}
+
+// GetHashV2 return SHA-256 info hash of v2 and hybrid torrents
+func (transfer *TransferStructure) GetHashV2() (hash string) {
+	torinfo, _ := bencode.EncodeString(transfer.TorrentFileRaw["info"])
+	h := sha256.New()
+	io.WriteString(h, torinfo)
+	hash = hex.EncodeToString(h.Sum(nil))
+	return
+}
+
 func (transfer *TransferStructure) HandlePieces() {
+	// real progress from uTorrent is much better than guessing with priorities
+	if transfer.HasHaveBitfield() {
+		transfer.FillPiecesFromBitfield(transfer.ResumeItem.Have)
+		return
+	}
 	if transfer.Fastresume.Unfinished != nil {
 		transfer.FillWholePieces(0)
 	} else {
@@ -221,6 +345,33 @@ func (transfer *TransferStructure) FillWholePieces(piecePrio int) {
 	}
 }
 
+// HasHaveBitfield check that resume item contain have bitfield that cover all pieces of torrent
+func (transfer *TransferStructure) HasHaveBitfield() bool {
+	if transfer.ResumeItem == nil || transfer.NumPieces == 0 {
+		return false
+	}
+	return int64(len(transfer.ResumeItem.Have))*8 >= transfer.NumPieces
+}
+
+// FillPiecesFromBitfield convert bitfield (high bit first) to libtorrent pieces and mark torrent unfinished if some pieces are missing
+func (transfer *TransferStructure) FillPiecesFromBitfield(bitfield []byte) {
+	transfer.Fastresume.Pieces = make([]byte, 0, transfer.NumPieces)
+	complete := true
+	for i := int64(0); i < transfer.NumPieces; i++ {
+		if bitfield[i/8]&(0x80>>uint(i%8)) != 0 {
+			transfer.Fastresume.Pieces = append(transfer.Fastresume.Pieces, byte(1))
+		} else {
+			transfer.Fastresume.Pieces = append(transfer.Fastresume.Pieces, byte(0))
+			complete = false
+		}
+	}
+	if complete {
+		transfer.Fastresume.Unfinished = nil
+	} else {
+		transfer.Fastresume.Unfinished = new([]interface{})
+	}
+}
+
 func (transfer *TransferStructure) FillPiecesParted() {
 	transfer.Fastresume.Pieces = make([]byte, 0, transfer.NumPieces)
 
@@ -276,6 +427,11 @@ func (transfer *TransferStructure) HandleSavePaths() {
 	if transfer.Magnet {
 		transfer.Fastresume.QBtContentLayout = "Original"
 		transfer.Fastresume.QbtSavePath = fileHelpers.Normalize(helpers.HandleCesu8(transfer.ResumeItem.Path), "/")
+		// torrent without torrent file. Path of resume is path of file or directory of torrent, so data will be found
+		// in parent directory with Original layout
+		if transfer.MissingTorrentFile {
+			transfer.Fastresume.QbtSavePath = fileHelpers.CutLastPath(transfer.Fastresume.QbtSavePath, "/")
+		}
 	} else {
 		var nameNormalized bool
 		transfer.Fastresume.Name, nameNormalized = normalization.FullNormalize(transfer.TorrentFile.GetTorrentName())
@@ -1924,6 +1924,91 @@ func TestTransferStructure_HandlePieces(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "006 partially downloaded with have bitfield",
+			newTransferStructure: &TransferStructure{
+				NumPieces: 10,
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{
+					FilePriority: []int64{1, 1},
+				},
+				ResumeItem: &utorrentStructs.ResumeItem{
+					Have: []byte{0xa5, 0xc0},
+				},
+				TorrentFile: &torrentStructures.Torrent{
+					Info: &torrentStructures.TorrentInfo{},
+				},
+			},
+			expected: &TransferStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{
+					FilePriority: []int64{1, 1},
+					Unfinished:   new([]interface{}),
+					Pieces: []byte{
+						byte(1),
+						byte(0),
+						byte(1),
+						byte(0),
+						byte(0),
+						byte(1),
+						byte(0),
+						byte(1),
+						byte(1),
+						byte(1),
+					},
+				},
+			},
+		},
+		{
+			name: "007 completed with have bitfield",
+			newTransferStructure: &TransferStructure{
+				NumPieces: 3,
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{
+					Unfinished: new([]interface{}),
+				},
+				ResumeItem: &utorrentStructs.ResumeItem{
+					Have: []byte{0xe0},
+				},
+				TorrentFile: &torrentStructures.Torrent{
+					Info: &torrentStructures.TorrentInfo{},
+				},
+			},
+			expected: &TransferStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{
+					Pieces: []byte{
+						byte(1),
+						byte(1),
+						byte(1),
+					},
+				},
+			},
+		},
+		{
+			name: "008 too short have bitfield fallback to priorities",
+			newTransferStructure: &TransferStructure{
+				NumPieces:  9,
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{},
+				ResumeItem: &utorrentStructs.ResumeItem{
+					Have: []byte{0x00},
+				},
+				TorrentFile: &torrentStructures.Torrent{
+					Info: &torrentStructures.TorrentInfo{},
+				},
+			},
+			expected: &TransferStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{
+					Pieces: []byte{
+						byte(1),
+						byte(1),
+						byte(1),
+						byte(1),
+						byte(1),
+						byte(1),
+						byte(1),
+						byte(1),
+						byte(1),
+					},
+				},
+			},
+		},
 	}
 	for _, testCase := range cases {
 		t.Run(testCase.name, func(t *testing.T) {
@@ -2486,3 +2571,234 @@ func TestTransferStructure_HandleState(t *testing.T) {
 	}
 
 }
+
+func TestTransferStructure_HandleSeedLimits(t *testing.T) {
+	type HandleSeedLimitsCase struct {
+		name       string
+		resumeItem *utorrentStructs.ResumeItem
+		expected   *qBittorrentStructures.QBittorrentFastresume
+	}
+	cases := []HandleSeedLimitsCase{
+		{
+			name:       "001 global seeding goals",
+			resumeItem: &utorrentStructs.ResumeItem{WantedRatio: 1500, WantedSeedtime: 3600},
+			expected:   &qBittorrentStructures.QBittorrentFastresume{QbtRatioLimit: -2000, QbtSeedingTimeLimit: -2},
+		},
+		{
+			name:       "002 own seeding goals",
+			resumeItem: &utorrentStructs.ResumeItem{OverrideSeed: 1, WantedRatio: 1500, WantedSeedtime: 3600},
+			expected:   &qBittorrentStructures.QBittorrentFastresume{QbtRatioLimit: 1500, QbtSeedingTimeLimit: 60},
+		},
+		{
+			name:       "003 own seeding goals without limits",
+			resumeItem: &utorrentStructs.ResumeItem{OverrideSeed: 1},
+			expected:   &qBittorrentStructures.QBittorrentFastresume{QbtRatioLimit: -1000, QbtSeedingTimeLimit: -1},
+		},
+		{
+			name:       "004 seeding time rounded up to minutes",
+			resumeItem: &utorrentStructs.ResumeItem{OverrideSeed: 1, WantedRatio: 2000, WantedSeedtime: 90},
+			expected:   &qBittorrentStructures.QBittorrentFastresume{QbtRatioLimit: 2000, QbtSeedingTimeLimit: 2},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferStructure := TransferStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{QbtRatioLimit: -2000, QbtSeedingTimeLimit: -2},
+				ResumeItem: testCase.resumeItem,
+			}
+			transferStructure.HandleSeedLimits()
+			if !reflect.DeepEqual(transferStructure.Fastresume, testCase.expected) {
+				changes, err := diff.Diff(transferStructure.Fastresume, testCase.expected, diff.DiscardComplexOrigin())
+				if err != nil {
+					t.Error(err.Error())
+				}
+				t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v\n Expect %#v\n Diff: %v\n", transferStructure.Fastresume, testCase.expected, spew.Sdump(changes))
+			}
+		})
+	}
+}
+
+func TestTransferStructure_HandleLimitsAndFlags(t *testing.T) {
+	type HandleLimitsCase struct {
+		name       string
+		resumeItem *utorrentStructs.ResumeItem
+		expected   *qBittorrentStructures.QBittorrentFastresume
+	}
+	var disabled, enabled int64 = 0, 1
+	cases := []HandleLimitsCase{
+		{
+			name:       "001 global settings",
+			resumeItem: &utorrentStructs.ResumeItem{},
+			expected:   &qBittorrentStructures.QBittorrentFastresume{DownloadRateLimit: -1, MaxUploads: 100},
+		},
+		{
+			name: "002 own limits and flags",
+			resumeItem: &utorrentStructs.ResumeItem{
+				UpSpeed:    51200,
+				DownSpeed:  102400,
+				UlSlots:    4,
+				SuperSeed:  1,
+				Sequential: 1,
+				Dht:        &disabled,
+				Pex:        &disabled,
+			},
+			expected: &qBittorrentStructures.QBittorrentFastresume{
+				UploadRateLimit:    51200,
+				DownloadRateLimit:  102400,
+				MaxUploads:         4,
+				SuperSeeding:       1,
+				SequentialDownload: 1,
+				DisableDht:         1,
+				DisablePex:         1,
+			},
+		},
+		{
+			name:       "003 enabled DHT and PEX",
+			resumeItem: &utorrentStructs.ResumeItem{Dht: &enabled, Pex: &enabled},
+			expected:   &qBittorrentStructures.QBittorrentFastresume{DownloadRateLimit: -1, MaxUploads: 100},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferStructure := TransferStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{DownloadRateLimit: -1, MaxUploads: 100},
+				ResumeItem: testCase.resumeItem,
+			}
+			transferStructure.HandleLimits()
+			transferStructure.HandleFlags()
+			if !reflect.DeepEqual(transferStructure.Fastresume, testCase.expected) {
+				changes, err := diff.Diff(transferStructure.Fastresume, testCase.expected, diff.DiscardComplexOrigin())
+				if err != nil {
+					t.Error(err.Error())
+				}
+				t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v\n Expect %#v\n Diff: %v\n", transferStructure.Fastresume, testCase.expected, spew.Sdump(changes))
+			}
+		})
+	}
+}
+
+func TestTransferStructure_HandleActivity(t *testing.T) {
+	type HandleActivityCase struct {
+		name       string
+		resumeItem *utorrentStructs.ResumeItem
+		expected   *qBittorrentStructures.QBittorrentFastresume
+	}
+	cases := []HandleActivityCase{
+		{
+			name: "001 seeding torrent",
+			resumeItem: &utorrentStructs.ResumeItem{
+				CompletedOn:      1600000100,
+				Downloaded:       1024,
+				Uploaded:         2048,
+				LastActive:       1600000500,
+				LastSeenComplete: 1600000400,
+				SeedTime:         3600,
+			},
+			expected: &qBittorrentStructures.QBittorrentFastresume{
+				CompletedTime:    1600000100,
+				FinishedTime:     3600,
+				LastDownload:     1600000100,
+				LastSeenComplete: 1600000400,
+				LastUpload:       1600000500,
+				SeedingTime:      3600,
+			},
+		},
+		{
+			name: "002 seeding torrent without last seen complete",
+			resumeItem: &utorrentStructs.ResumeItem{
+				CompletedOn: 1600000100,
+				Uploaded:    2048,
+				LastActive:  1600000500,
+			},
+			expected: &qBittorrentStructures.QBittorrentFastresume{
+				CompletedTime:    1600000100,
+				LastSeenComplete: 1600000100,
+				LastUpload:       1600000500,
+			},
+		},
+		{
+			name: "003 unfinished torrent",
+			resumeItem: &utorrentStructs.ResumeItem{
+				Downloaded: 1024,
+				LastActive: 1600000500,
+			},
+			expected: &qBittorrentStructures.QBittorrentFastresume{
+				LastDownload: 1600000500,
+				Unfinished:   new([]interface{}),
+			},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferStructure := TransferStructure{
+				Fastresume: &qBittorrentStructures.QBittorrentFastresume{CompletedTime: testCase.resumeItem.CompletedOn},
+				ResumeItem: testCase.resumeItem,
+			}
+			transferStructure.HandleActivity()
+			transferStructure.HandleCompleted()
+			if !reflect.DeepEqual(transferStructure.Fastresume, testCase.expected) {
+				changes, err := diff.Diff(transferStructure.Fastresume, testCase.expected, diff.DiscardComplexOrigin())
+				if err != nil {
+					t.Error(err.Error())
+				}
+				t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v\n Expect %#v\n Diff: %v\n", transferStructure.Fastresume, testCase.expected, spew.Sdump(changes))
+			}
+		})
+	}
+}
+
+func TestTransferStructure_HandleWebSeeds(t *testing.T) {
+	type HandleWebSeedsCase struct {
+		name              string
+		torrentFile       *torrentStructures.Torrent
+		resumeItem        *utorrentStructs.ResumeItem
+		expectedUrlList   []string
+		expectedHttpSeeds []string
+	}
+	cases := []HandleWebSeedsCase{
+		{
+			name:        "001 without seeds",
+			torrentFile: &torrentStructures.Torrent{},
+			resumeItem:  &utorrentStructs.ResumeItem{},
+		},
+		{
+			name: "002 seeds of torrent file",
+			torrentFile: &torrentStructures.Torrent{
+				UrlList:   "http://mirror1.org/files/",
+				HttpSeeds: []interface{}{"http://seed1.org/seed.php"},
+			},
+			resumeItem:        &utorrentStructs.ResumeItem{},
+			expectedUrlList:   []string{"http://mirror1.org/files/"},
+			expectedHttpSeeds: []string{"http://seed1.org/seed.php"},
+		},
+		{
+			name: "003 merged and deduplicated seeds",
+			torrentFile: &torrentStructures.Torrent{
+				UrlList:   []interface{}{"http://mirror1.org/files/", "http://mirror2.org/files/"},
+				HttpSeeds: []interface{}{"http://seed1.org/seed.php"},
+			},
+			resumeItem: &utorrentStructs.ResumeItem{
+				UrlList:   []string{"http://mirror2.org/files/", "http://mirror3.org/files/"},
+				HttpSeeds: []string{"http://seed1.org/seed.php"},
+			},
+			expectedUrlList:   []string{"http://mirror1.org/files/", "http://mirror2.org/files/", "http://mirror3.org/files/"},
+			expectedHttpSeeds: []string{"http://seed1.org/seed.php"},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferStructure := TransferStructure{
+				Fastresume:  &qBittorrentStructures.QBittorrentFastresume{},
+				TorrentFile: testCase.torrentFile,
+				ResumeItem:  testCase.resumeItem,
+			}
+			transferStructure.HandleWebSeeds()
+			if !reflect.DeepEqual(transferStructure.Fastresume.UrlList, testCase.expectedUrlList) {
+				t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v\n Expect %#v\n", transferStructure.Fastresume.UrlList, testCase.expectedUrlList)
+			}
+			if !reflect.DeepEqual(transferStructure.Fastresume.HttpSeeds, testCase.expectedHttpSeeds) {
+				t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v\n Expect %#v\n", transferStructure.Fastresume.HttpSeeds, testCase.expectedHttpSeeds)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package transfer
+
+import (
+	"os"
+
+	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/verification"
+)
+
+// HandleVerify hash payload on disk and replace pieces that was calculated from resume.dat with exact bitfield.
+// If all pieces are valid torrent will be added in seed mode, and qBittorrent doesn't need recheck it
+func (transfer *TransferStructure) HandleVerify() {
+	if transfer.Hasher == nil || transfer.Magnet {
+		return
+	}
+	info := transfer.TorrentFile.Info
+
+	var results []bool
+	if len(info.Pieces) > 0 {
+		results = transfer.Hasher.VerifyV1(transfer.GetV1FilesOnDisk(), info.PieceLength, info.Pieces)
+	}
+	if transfer.TorrentFile.IsV2OrHybryd() {
+		var pieceLayers map[string]interface{}
+		if transfer.TorrentFile.PieceLayers != nil {
+			pieceLayers = *transfer.TorrentFile.PieceLayers
+		}
+		v2Results := transfer.Hasher.VerifyV2(transfer.GetV2FilesOnDisk(), info.PieceLength, pieceLayers)
+		if results == nil {
+			// pure v2 torrent haven't v1 pieces at all
+			results = v2Results
+			transfer.NumPieces = int64(len(results))
+		} else if len(results) == len(v2Results) {
+			// hybrid torrent pieces are aligned, so both hashes must match
+			for i := range results {
+				results[i] = results[i] && v2Results[i]
+			}
+		}
+	}
+	if len(results) == 0 {
+		return
+	}
+
+	complete := true
+	transfer.Fastresume.Pieces = make([]byte, 0, len(results))
+	for _, valid := range results {
+		if valid {
+			transfer.Fastresume.Pieces = append(transfer.Fastresume.Pieces, byte(1))
+		} else {
+			transfer.Fastresume.Pieces = append(transfer.Fastresume.Pieces, byte(0))
+			complete = false
+		}
+	}
+	if complete {
+		transfer.Fastresume.SeedMode = 1
+		transfer.Fastresume.Unfinished = nil
+	} else {
+		transfer.Fastresume.SeedMode = 0
+		transfer.Fastresume.Unfinished = new([]interface{})
+	}
+}
+
+// GetFilePathOnDisk return path of file with index from GetFileList, using save path and mapped files.
+// HandleSavePaths must be called before
+func (transfer *TransferStructure) GetFilePathOnDisk(index int) string {
+	separator := string(os.PathSeparator)
+	if index < len(transfer.Fastresume.MappedFiles) && transfer.Fastresume.MappedFiles[index] != "" {
+		mappedFile := transfer.Fastresume.MappedFiles[index]
+		if fileHelpers.IsAbs(mappedFile) {
+			return fileHelpers.Normalize(mappedFile, separator)
+		}
+		return fileHelpers.Join([]string{transfer.Fastresume.SavePath, mappedFile}, separator)
+	}
+	if transfer.TorrentFile.IsSingle() {
+		return fileHelpers.Join([]string{transfer.Fastresume.SavePath, transfer.Fastresume.Name}, separator)
+	}
+	fileList, _ := transfer.TorrentFile.GetFileList()
+	if transfer.Fastresume.QBtContentLayout == "NoSubfolder" {
+		return fileHelpers.Join([]string{transfer.Fastresume.SavePath, fileList[index]}, separator)
+	}
+	return fileHelpers.Join([]string{transfer.Fastresume.SavePath, transfer.Fastresume.Name, fileList[index]}, separator)
+}
+
+// GetV1FilesOnDisk return files in order of v1 info, including padding files
+func (transfer *TransferStructure) GetV1FilesOnDisk() []verification.File {
+	info := transfer.TorrentFile.Info
+	if info.Files == nil {
+		return []verification.File{{Path: transfer.GetFilePathOnDisk(0), Length: info.Length}}
+	}
+	files := make([]verification.File, 0, len(info.Files))
+	index := 0
+	for _, file := range info.Files {
+		if file.IsPadding() {
+			files = append(files, verification.File{Length: file.Length, Padding: true})
+			// hybrid torrents file list is built from file tree that doesn't contain padding files
+			if !transfer.TorrentFile.IsV2OrHybryd() {
+				index++
+			}
+			continue
+		}
+		files = append(files, verification.File{Path: transfer.GetFilePathOnDisk(index), Length: file.Length})
+		index++
+	}
+	return files
+}
+
+// GetV2FilesOnDisk return files in order of v2 file tree with pieces roots
+func (transfer *TransferStructure) GetV2FilesOnDisk() []verification.File {
+	fileList, _ := transfer.TorrentFile.GetFileListWB()
+	roots := transfer.TorrentFile.GetPiecesRoots()
+	files := make([]verification.File, 0, len(fileList))
+	for index, file := range fileList {
+		var root []byte
+		if index < len(roots) {
+			root = roots[index]
+		}
+		files = append(files, verification.File{Path: transfer.GetFilePathOnDisk(index), Length: file.Length, PiecesRoot: root})
+	}
+	return files
+}
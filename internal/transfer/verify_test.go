@@ -0,0 +1,74 @@
+package transfer
+
+import (
+	"testing"
+
+	"github.com/rumanzo/bt2qbt/internal/options"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+	"github.com/rumanzo/bt2qbt/pkg/verification"
+)
+
+func TestTransferStructure_HandleVerify(t *testing.T) {
+	type HandleVerifyCase struct {
+		name             string
+		torrentPath      string
+		resumePath       string
+		expectedPieces   int
+		expectedSeedMode int64
+	}
+	cases := []HandleVerifyCase{
+		{
+			name:             "001 testdir v1",
+			torrentPath:      "../../test/data/testdir_v1.torrent",
+			resumePath:       "../../test/data/testdir",
+			expectedPieces:   1,
+			expectedSeedMode: 1,
+		},
+		{
+			name:             "002 testdir v2",
+			torrentPath:      "../../test/data/testdir_v2.torrent",
+			resumePath:       "../../test/data/testdir",
+			expectedPieces:   9,
+			expectedSeedMode: 1,
+		},
+		{
+			name:             "003 testdir hybrid",
+			torrentPath:      "../../test/data/testdir_hybrid.torrent",
+			resumePath:       "../../test/data/testdir",
+			expectedPieces:   9,
+			expectedSeedMode: 1,
+		},
+		{
+			name:             "004 testdir v1 with wrong save path",
+			torrentPath:      "../../test/data/testdir_v1.torrent",
+			resumePath:       "../../test/data/not_exists/testdir",
+			expectedPieces:   1,
+			expectedSeedMode: 0,
+		},
+	}
+	hasher := verification.NewHasher(2)
+	defer hasher.Close()
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			transferStructure := CreateEmptyNewTransferStructure()
+			transferStructure.Opts = &options.Opts{PathSeparator: `/`}
+			transferStructure.Hasher = hasher
+			transferStructure.ResumeItem = &utorrentStructs.ResumeItem{
+				Path: testCase.resumePath,
+				Prio: make([]byte, 18),
+			}
+			if err := helpers.DecodeTorrentFile(testCase.torrentPath, transferStructure.TorrentFile); err != nil {
+				t.Fatalf("Can't decode torrent file with error: %v", err)
+			}
+			transferStructure.HandleStructures()
+			transferStructure.HandleVerify()
+			if len(transferStructure.Fastresume.Pieces) != testCase.expectedPieces {
+				t.Fatalf("Unexpected pieces count. Got %v, expect %v", len(transferStructure.Fastresume.Pieces), testCase.expectedPieces)
+			}
+			if transferStructure.Fastresume.SeedMode != testCase.expectedSeedMode {
+				t.Fatalf("Unexpected seed mode. Got %v, expect %v. Pieces: %v", transferStructure.Fastresume.SeedMode, testCase.expectedSeedMode, transferStructure.Fastresume.Pieces)
+			}
+		})
+	}
+}
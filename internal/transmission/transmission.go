@@ -0,0 +1,174 @@
+package transmission
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/resumeHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/transmissionStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+)
+
+// ReadResumeItems read transmission config directory with resume and torrents subdirectories and convert
+// every resume file to uTorrent resume item. Keys are torrent files paths relative to directory. Resume files that
+// can't be read are skipped and returned with error by key
+func ReadResumeItems(dir string) (map[string]*utorrentStructs.ResumeItem, map[string]error, error) {
+	resumePaths, err := filepath.Glob(filepath.Join(dir, "resume", "*.resume"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(resumePaths) == 0 {
+		return nil, nil, fmt.Errorf("can't find transmission resume files in %v", filepath.Join(dir, "resume"))
+	}
+	sort.Strings(resumePaths)
+
+	resumeItems := map[string]*utorrentStructs.ResumeItem{}
+	skipped := map[string]error{}
+	for _, resumePath := range resumePaths {
+		key := fileHelpers.Join([]string{"torrents", strings.TrimSuffix(filepath.Base(resumePath), ".resume") + ".torrent"}, `/`)
+		resumeItem, err := ReadResumeItem(resumePath, filepath.Join(dir, key))
+		if err != nil {
+			log.Printf("Can't read transmission resume %v. Err: %v\n", resumePath, err)
+			skipped[key] = err
+			continue
+		}
+		resumeItems[key] = resumeItem
+	}
+	return resumeItems, skipped, nil
+}
+
+// ReadResumeItem decode transmission resume and its torrent file, and convert them to uTorrent resume item
+func ReadResumeItem(resumePath string, torrentPath string) (*utorrentStructs.ResumeItem, error) {
+	resume := &transmissionStructures.TransmissionResume{}
+	if err := helpers.DecodeTorrentFile(resumePath, resume); err != nil {
+		return nil, err
+	}
+	torrentFile, torrentFileRaw, err := resumeHelpers.ReadTorrentFile(torrentPath)
+	if err != nil {
+		return nil, err
+	}
+	return ConvertResume(resume, torrentFile, torrentFileRaw)
+}
+
+// ConvertResume map transmission resume to uTorrent resume item. Transmission doesn't store trackers,
+// they are taken from torrent file
+func ConvertResume(resume *transmissionStructures.TransmissionResume, torrentFile *torrentStructures.Torrent, torrentFileRaw map[string]interface{}) (*utorrentStructs.ResumeItem, error) {
+	hash, err := resumeHelpers.InfoHash(torrentFile, torrentFileRaw)
+	if err != nil {
+		return nil, err
+	}
+
+	resumeItem := &utorrentStructs.ResumeItem{
+		AddedOn:     resume.AddedDate,
+		CompletedOn: resume.DoneDate,
+		Downloaded:  resume.Downloaded,
+		Uploaded:    resume.Uploaded,
+		Info:        hash,
+		Labels:      resume.Labels,
+		Time:        resume.ActivityDate,
+	}
+	if resume.Paused == 1 {
+		resumeItem.Started = 0
+	} else {
+		resumeItem.Started = 2
+	}
+
+	name := torrentFile.GetTorrentName()
+	if resume.Name != "" {
+		name = resume.Name
+	}
+	resumeItem.Path = fileHelpers.Join([]string{resume.Destination, name}, `/`)
+
+	fileList := resumeHelpers.FileList(torrentFile)
+	resumeItem.Prio = resumeHelpers.Priority(torrentFile, convertPriority(resume, len(fileList)))
+	if !torrentFile.IsSingle() {
+		resumeItem.Targets = resumeHelpers.Targets(resume.Files, fileList)
+	}
+	resumeItem.Have = convertProgress(resume.Progress, int64(len(torrentFile.Info.Pieces)/20), torrentFile.Info.PieceLength, torrentSize(torrentFile))
+	resumeItem.Trackers = resumeHelpers.Trackers(torrentFileRaw)
+	return resumeItem, nil
+}
+
+// convertPriority map transmission priorities and dnd flags to uTorrent priorities
+func convertPriority(resume *transmissionStructures.TransmissionResume, numFiles int) []byte {
+	prio := make([]byte, 0, numFiles)
+	for i := 0; i < numFiles; i++ {
+		var p int64
+		if i < len(resume.Priority) {
+			p = resume.Priority[i]
+		}
+		switch {
+		case i < len(resume.Dnd) && resume.Dnd[i] == 1:
+			prio = append(prio, 128) // don't download
+		case p < 0:
+			prio = append(prio, 4) // low
+		case p > 0:
+			prio = append(prio, 15) // high
+		default:
+			prio = append(prio, 8) // normal
+		}
+	}
+	return prio
+}
+
+// blockSize size of transmission block
+const blockSize = 16 * 1024
+
+// convertProgress return have bitfield for numPieces pieces or nil if progress is unknown. Transmission store downloaded
+// blocks, piece is downloaded if all its blocks are downloaded. Have and bitfield of pieces are used only if resume
+// doesn't contain blocks
+func convertProgress(progress transmissionStructures.Progress, numPieces int64, pieceLength int64, size int64) []byte {
+	switch blocks := progress.Blocks.(type) {
+	case string:
+		switch blocks {
+		case "all":
+			return resumeHelpers.HaveAll(numPieces)
+		case "none":
+			return make([]byte, (numPieces+7)/8)
+		default:
+			return convertBlocks([]byte(blocks), numPieces, pieceLength, size)
+		}
+	}
+	if progress.Have == "all" {
+		return resumeHelpers.HaveAll(numPieces)
+	}
+	if len(progress.Bitfield) > 0 {
+		return progress.Bitfield
+	}
+	return nil
+}
+
+// convertBlocks convert bitfield of blocks to bitfield of pieces
+func convertBlocks(blocks []byte, numPieces int64, pieceLength int64, size int64) []byte {
+	have := make([]byte, (numPieces+7)/8)
+	for piece := int64(0); piece < numPieces; piece++ {
+		start := piece * pieceLength
+		end := start + pieceLength
+		if end > size {
+			end = size
+		}
+		downloaded := end > start
+		for block := start / blockSize; downloaded && block <= (end-1)/blockSize; block++ {
+			downloaded = block/8 < int64(len(blocks)) && blocks[block/8]&(0x80>>uint(block%8)) != 0
+		}
+		if downloaded {
+			have[piece/8] |= 0x80 >> uint(piece%8)
+		}
+	}
+	return have
+}
+
+// torrentSize return size of all files of torrent including padding files
+func torrentSize(torrentFile *torrentStructures.Torrent) int64 {
+	size := torrentFile.Info.Length
+	for _, file := range torrentFile.Info.Files {
+		size += file.Length
+	}
+	return size
+}
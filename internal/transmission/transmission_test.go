@@ -0,0 +1,197 @@
+package transmission
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/r3labs/diff/v2"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
+	"github.com/rumanzo/bt2qbt/pkg/transmissionStructures"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+)
+
+func TestConvertResume(t *testing.T) {
+	type ConvertResumeCase struct {
+		name        string
+		torrentPath string
+		resume      *transmissionStructures.TransmissionResume
+		expected    *utorrentStructs.ResumeItem
+		mustFail    bool
+	}
+	cases := []ConvertResumeCase{
+		{
+			name:        "001 multi file torrent with renamed file and not wanted files",
+			torrentPath: "../../test/data/testdir_v1.torrent",
+			resume: &transmissionStructures.TransmissionResume{
+				AddedDate:   1650146608,
+				DoneDate:    1650146700,
+				Destination: "/home/user/Downloads",
+				Dnd:         []int64{0, 1, 0, 0, 0, 0, 0, 0, 0},
+				Priority:    []int64{-1, 0, 1, 0, 0, 0, 0, 0, 0},
+				Files:       []string{"testdir/renamed.txt", "testdir/testfile2.txt"},
+				Labels:      []string{"label1"},
+				Paused:      1,
+				Uploaded:    100,
+				Downloaded:  200,
+				Progress:    transmissionStructures.Progress{Have: "all"},
+			},
+			expected: &utorrentStructs.ResumeItem{
+				AddedOn:     1650146608,
+				CompletedOn: 1650146700,
+				Downloaded:  200,
+				Uploaded:    100,
+				Labels:      []string{"label1"},
+				Path:        "/home/user/Downloads/testdir",
+				Prio:        []byte{4, 128, 15, 8, 8, 8, 8, 8, 8},
+				Started:     0,
+				Targets:     [][]interface{}{{int64(0), "renamed.txt"}},
+				Have:        []byte{0x80},
+			},
+		},
+		{
+			name:        "002 single file renamed torrent with bitfield",
+			torrentPath: "../../test/data/testfile1_single_v1.torrent",
+			resume: &transmissionStructures.TransmissionResume{
+				Destination: "/home/user/Downloads",
+				Name:        "renamed.txt",
+				Progress:    transmissionStructures.Progress{Bitfield: []byte{0x00}},
+			},
+			expected: &utorrentStructs.ResumeItem{
+				Path:    "/home/user/Downloads/renamed.txt",
+				Prio:    []byte{8},
+				Started: 2,
+				Have:    []byte{0x00},
+			},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			torrentFile := &torrentStructures.Torrent{}
+			if err := helpers.DecodeTorrentFile(testCase.torrentPath, torrentFile); err != nil {
+				t.Fatalf("Can't decode torrent file: %v", err)
+			}
+			torrentFileRaw := map[string]interface{}{}
+			if err := helpers.DecodeTorrentFile(testCase.torrentPath, torrentFileRaw); err != nil {
+				t.Fatalf("Can't decode torrent file: %v", err)
+			}
+			resumeItem, err := ConvertResume(testCase.resume, torrentFile, torrentFileRaw)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			// hash and trackers are taken from torrent file as is
+			testCase.expected.Info = resumeItem.Info
+			testCase.expected.Trackers = resumeItem.Trackers
+			equal := reflect.DeepEqual(testCase.expected, resumeItem)
+			if !equal && !testCase.mustFail {
+				changes, err := diff.Diff(resumeItem, testCase.expected, diff.DiscardComplexOrigin())
+				if err != nil {
+					t.Error(err.Error())
+				}
+				t.Fatalf("Unexpected error: structures aren't equal:\nGot: %#v\nExpect %#v\nDiff: %v\n", resumeItem, testCase.expected, spew.Sdump(changes))
+			} else if equal && testCase.mustFail {
+				t.Fatalf("Unexpected error: structures are equal, but they shouldn't\nGot: %v\n", spew.Sdump(resumeItem))
+			}
+		})
+	}
+}
+
+func TestReadResumeItems(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "resume"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "torrents"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := helpers.CopyFile("../../test/data/testdir_v1.torrent", filepath.Join(dir, "torrents", "testdir.torrent")); err != nil {
+		t.Fatal(err)
+	}
+	resume := &transmissionStructures.TransmissionResume{
+		Destination: "/home/user/Downloads",
+		Progress:    transmissionStructures.Progress{Blocks: string([]byte{0x80})},
+	}
+	if err := helpers.EncodeTorrentFile(filepath.Join(dir, "resume", "testdir.resume"), resume); err != nil {
+		t.Fatal(err)
+	}
+	// resume without torrent file must be skipped
+	if err := helpers.EncodeTorrentFile(filepath.Join(dir, "resume", "lost.resume"), resume); err != nil {
+		t.Fatal(err)
+	}
+
+	resumeItems, skipped, err := ReadResumeItems(dir)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resumeItems) != 1 {
+		t.Fatalf("Unexpected resume items count %v", len(resumeItems))
+	}
+	if _, ok := skipped["torrents/lost.torrent"]; !ok || len(skipped) != 1 {
+		t.Fatalf("Unexpected skipped resume files: %v", spew.Sdump(skipped))
+	}
+	if resumeItem, ok := resumeItems["torrents/testdir.torrent"]; !ok || resumeItem.Path != "/home/user/Downloads/testdir" ||
+		!reflect.DeepEqual(resumeItem.Have, []byte{0x80}) {
+		t.Fatalf("Unexpected resume items: %v", spew.Sdump(resumeItems))
+	}
+}
+
+func TestConvertProgress(t *testing.T) {
+	type ConvertProgressCase struct {
+		name     string
+		progress transmissionStructures.Progress
+		expected []byte
+	}
+	// three pieces of two blocks, last piece is shorter
+	numPieces, pieceLength, size := int64(3), int64(2*blockSize), int64(4*blockSize+20000)
+	cases := []ConvertProgressCase{
+		{
+			name:     "001 all blocks",
+			progress: transmissionStructures.Progress{Blocks: "all"},
+			expected: []byte{0xe0},
+		},
+		{
+			name:     "002 no blocks",
+			progress: transmissionStructures.Progress{Blocks: "none"},
+			expected: []byte{0x00},
+		},
+		{
+			name:     "003 partial blocks bitfield",
+			progress: transmissionStructures.Progress{Blocks: string([]byte{0xec})},
+			expected: []byte{0xa0},
+		},
+		{
+			name:     "004 blocks bitfield shorter than torrent",
+			progress: transmissionStructures.Progress{Blocks: string([]byte{0xc0})},
+			expected: []byte{0x80},
+		},
+		{
+			name:     "005 blocks are preferred to legacy bitfield",
+			progress: transmissionStructures.Progress{Blocks: "none", Bitfield: []byte{0xe0}},
+			expected: []byte{0x00},
+		},
+		{
+			name:     "006 legacy have",
+			progress: transmissionStructures.Progress{Have: "all"},
+			expected: []byte{0xe0},
+		},
+		{
+			name:     "007 legacy bitfield",
+			progress: transmissionStructures.Progress{Bitfield: []byte{0x40}},
+			expected: []byte{0x40},
+		},
+		{
+			name: "008 unknown progress",
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			have := convertProgress(testCase.progress, numPieces, pieceLength, size)
+			if !reflect.DeepEqual(have, testCase.expected) {
+				t.Fatalf("Unexpected have. Got %#v, expect %#v", have, testCase.expected)
+			}
+		})
+	}
+}
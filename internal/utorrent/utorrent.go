@@ -0,0 +1,209 @@
+package utorrent
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+	"github.com/zeebo/bencode"
+)
+
+// EntryError resume.dat entry that can't be decoded. Field is empty if entry itself has wrong type
+type EntryError struct {
+	Key   string
+	Field string
+	Err   error
+}
+
+func (e *EntryError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("Can't decode resume.dat entry %v: %v", e.Key, e.Err)
+	}
+	return fmt.Sprintf("Can't decode resume.dat entry %v: field %v: %v", e.Key, e.Field, e.Err)
+}
+
+// ErrFileGuard resume.dat was decoded, but its .fileguard doesn't match content. Usually uTorrent was killed while
+// writing it
+var ErrFileGuard = errors.New("fileguard doesn't match content")
+
+// ReadResumeItems read uTorrent resume.dat from directory. Every entry is decoded separately, entries that can't be
+// decoded are skipped and returned with *EntryError by key. If resume.dat is corrupted or its fileguard doesn't match,
+// resume.dat.old that uTorrent keeps as previous copy is offered in interactive mode and used automatically otherwise
+func ReadResumeItems(dir string, interactive bool) (map[string]*utorrentStructs.ResumeItem, map[string]error, error) {
+	resumeFilePath := filepath.Join(dir, "resume.dat")
+	if _, err := os.Stat(resumeFilePath); os.IsNotExist(err) {
+		return nil, nil, fmt.Errorf("can't find uTorrent\\Bittorrent resume file")
+	}
+	resumeFile, err := ReadResumeFile(resumeFilePath)
+	if err != nil {
+		log.Printf("uTorrent\\Bittorrent resume file is corrupted: %v\n", err)
+		oldResumeFile, oldErr := ReadResumeFile(resumeFilePath + ".old")
+		switch {
+		case oldErr == nil && (!interactive || confirm("Use previous copy resume.dat.old instead? [y/N] ")):
+			log.Println("Using uTorrent\\Bittorrent resume file resume.dat.old")
+			resumeFile = oldResumeFile
+		case resumeFile == nil:
+			return nil, nil, fmt.Errorf("can't decode uTorrent\\Bittorrent resume file: %v", err)
+		default:
+			// content was decoded, it's better than nothing
+			log.Println("Using uTorrent\\Bittorrent resume file resume.dat with mismatched fileguard")
+		}
+	}
+	resumeItems, entryErrors := DecodeResumeItems(resumeFile)
+	skipped := map[string]error{}
+	for _, entryError := range entryErrors {
+		log.Println(entryError)
+		skipped[entryError.Key] = entryError
+	}
+	return resumeItems, skipped, nil
+}
+
+// ReadResumeFile decode resume.dat and check its .fileguard. If fileguard doesn't match, decoded resume is returned
+// with ErrFileGuard
+func ReadResumeFile(path string) (map[string]interface{}, error) {
+	resumeFile := map[string]interface{}{}
+	if err := helpers.DecodeTorrentFile(path, resumeFile); err != nil {
+		return nil, err
+	}
+	if fileGuard, ok := resumeFile[".fileguard"].(string); ok {
+		if expected, err := helpers.FileGuard(resumeFile); err == nil && !strings.EqualFold(fileGuard, expected) {
+			return resumeFile, fmt.Errorf("%v: %w", path, ErrFileGuard)
+		}
+	}
+	return resumeFile, nil
+}
+
+func confirm(question string) bool {
+	fmt.Print(question)
+	var answer string
+	fmt.Scanln(&answer)
+	return strings.EqualFold(answer, "y") || strings.EqualFold(answer, "yes")
+}
+
+// DecodeResumeItems decode every torrent entry of resume.dat
+func DecodeResumeItems(resumeFile map[string]interface{}) (map[string]*utorrentStructs.ResumeItem, []*EntryError) {
+	resumeItems := map[string]*utorrentStructs.ResumeItem{}
+	var entryErrors []*EntryError
+	for key, value := range resumeFile {
+		// hate utorrent for heterogeneous resume.dat scheme
+		if key == ".fileguard" || key == "rec" {
+			continue
+		}
+		resumeItem, err := DecodeResumeItem(key, value)
+		if err != nil {
+			entryErrors = append(entryErrors, err)
+			continue
+		}
+		resumeItems[key] = resumeItem
+	}
+	sort.Slice(entryErrors, func(i, j int) bool { return entryErrors[i].Key < entryErrors[j].Key })
+	return resumeItems, entryErrors
+}
+
+// DecodeResumeItem decode resume.dat entry field by field, so error contain field with unexpected type
+func DecodeResumeItem(key string, value interface{}) (*utorrentStructs.ResumeItem, *EntryError) {
+	entry, ok := value.(map[string]interface{})
+	if !ok {
+		return nil, &EntryError{Key: key, Err: fmt.Errorf("expected dictionary, got %T", value)}
+	}
+	resumeItem := &utorrentStructs.ResumeItem{}
+	itemValue := reflect.ValueOf(resumeItem).Elem()
+	itemType := itemValue.Type()
+	for i := 0; i < itemType.NumField(); i++ {
+		field := strings.Split(itemType.Field(i).Tag.Get("bencode"), ",")[0]
+		fieldValue, ok := entry[field]
+		if !ok {
+			continue
+		}
+		fieldValue = normalizeField(field, fieldValue)
+		encoded, err := bencode.EncodeBytes(fieldValue)
+		if err != nil {
+			return nil, &EntryError{Key: key, Field: field, Err: err}
+		}
+		if err = bencode.DecodeBytes(encoded, itemValue.Field(i).Addr().Interface()); err != nil {
+			return nil, &EntryError{Key: key, Field: field, Err: fmt.Errorf("unexpected %T value: %v", fieldValue, err)}
+		}
+	}
+	return resumeItem, nil
+}
+
+// normalizeField convert known alternative shapes of fields to shapes of ResumeItem
+func normalizeField(field string, value interface{}) interface{} {
+	switch field {
+	case "label":
+		// some versions store list of labels in label
+		if list, ok := value.([]interface{}); ok {
+			if labels := stringList(list); len(labels) > 0 {
+				return labels[0]
+			}
+			return ""
+		}
+	case "labels":
+		switch v := value.(type) {
+		case string:
+			if v == "" {
+				return []string{}
+			}
+			return []string{v}
+		case []interface{}:
+			return stringList(v)
+		}
+	case "trackers", "url-list", "httpseeds":
+		// trackers can be string, list of strings or list of tiers
+		return helpers.GetStrings(value)
+	case "prio", "have":
+		// list of integers instead of string
+		if list, ok := value.([]interface{}); ok {
+			bytes := make([]byte, 0, len(list))
+			for _, element := range list {
+				number, ok := element.(int64)
+				if !ok {
+					return value
+				}
+				bytes = append(bytes, byte(number))
+			}
+			return string(bytes)
+		}
+	default:
+		// numbers stored as strings
+		if str, ok := value.(string); ok {
+			if number, err := strconv.ParseInt(str, 10, 64); err == nil && isIntegerField(field) {
+				return number
+			}
+		}
+	}
+	return value
+}
+
+// stringList return strings of list, other elements are skipped
+func stringList(list []interface{}) []string {
+	result := []string{}
+	for _, element := range list {
+		if str, ok := element.(string); ok {
+			result = append(result, str)
+		}
+	}
+	return result
+}
+
+func isIntegerField(field string) bool {
+	itemType := reflect.TypeOf(utorrentStructs.ResumeItem{})
+	for i := 0; i < itemType.NumField(); i++ {
+		if strings.Split(itemType.Field(i).Tag.Get("bencode"), ",")[0] == field {
+			fieldType := itemType.Field(i).Type
+			if fieldType.Kind() == reflect.Ptr {
+				fieldType = fieldType.Elem()
+			}
+			return fieldType.Kind() == reflect.Int64
+		}
+	}
+	return false
+}
@@ -0,0 +1,268 @@
+package utorrent
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/r3labs/diff/v2"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/utorrentStructs"
+	"github.com/zeebo/bencode"
+)
+
+func TestDecodeResumeItem(t *testing.T) {
+	type DecodeCase struct {
+		name          string
+		mustFail      bool
+		value         interface{}
+		expected      *utorrentStructs.ResumeItem
+		expectedField string
+	}
+	var disabled, enabled int64 = 0, 1
+	cases := []DecodeCase{
+		{
+			name: "001 regular entry",
+			value: map[string]interface{}{
+				"added_on": int64(1600000000),
+				"caption":  "caption",
+				"info":     "01234567890123456789",
+				"label":    "films",
+				"labels":   []interface{}{"tag1", "tag2"},
+				"path":     `D:\torrents\test`,
+				"prio":     "\x08\x0f",
+				"trackers": []interface{}{"http://tracker.org/announce"},
+				"unknown":  int64(1),
+			},
+			expected: &utorrentStructs.ResumeItem{
+				AddedOn:  1600000000,
+				Caption:  "caption",
+				Info:     "01234567890123456789",
+				Label:    "films",
+				Labels:   []string{"tag1", "tag2"},
+				Path:     `D:\torrents\test`,
+				Prio:     []byte{8, 15},
+				Trackers: []string{"http://tracker.org/announce"},
+			},
+		},
+		{
+			name: "002 alternative shapes",
+			value: map[string]interface{}{
+				"added_on": "1600000000",
+				"label":    []interface{}{"films", "music"},
+				"labels":   "tag1",
+				"prio":     []interface{}{int64(8), int64(15)},
+				"trackers": []interface{}{[]interface{}{"http://tracker.org/announce", "http://tracker2.org/announce"}, int64(1)},
+				"url-list": "http://mirror.org/files/",
+			},
+			expected: &utorrentStructs.ResumeItem{
+				AddedOn:  1600000000,
+				Label:    "films",
+				Labels:   []string{"tag1"},
+				Prio:     []byte{8, 15},
+				Trackers: []string{"http://tracker.org/announce", "http://tracker2.org/announce"},
+				UrlList:  []string{"http://mirror.org/files/"},
+			},
+		},
+		{
+			name:     "003 entry isn't dictionary. mustFail",
+			value:    int64(1),
+			mustFail: true,
+		},
+		{
+			name: "004 path with unexpected type. mustFail",
+			value: map[string]interface{}{
+				"path": map[string]interface{}{"a": "b"},
+			},
+			mustFail:      true,
+			expectedField: "path",
+		},
+		{
+			name: "005 added_on with unexpected type. mustFail",
+			value: map[string]interface{}{
+				"added_on": "yesterday",
+			},
+			mustFail:      true,
+			expectedField: "added_on",
+		},
+		{
+			name: "006 transfer settings",
+			value: map[string]interface{}{
+				"dht":        int64(0),
+				"downspeed":  int64(102400),
+				"pex":        "1",
+				"sequential": int64(1),
+				"superseed":  int64(1),
+				"ulslots":    int64(4),
+			},
+			expected: &utorrentStructs.ResumeItem{
+				Dht:        &disabled,
+				DownSpeed:  102400,
+				Pex:        &enabled,
+				Sequential: 1,
+				SuperSeed:  1,
+				UlSlots:    4,
+			},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			resumeItem, err := DecodeResumeItem("test.torrent", testCase.value)
+			if err != nil && !testCase.mustFail {
+				t.Fatalf("Unexpected error: %v", err)
+			} else if err == nil && testCase.mustFail {
+				t.Fatalf("Test must fail, but it doesn't")
+			}
+			if testCase.mustFail {
+				if err.Field != testCase.expectedField {
+					t.Fatalf("Unexpected field at fault. Got %v, expect %v", err.Field, testCase.expectedField)
+				}
+				return
+			}
+			changes, err2 := diff.Diff(resumeItem, testCase.expected, diff.DiscardComplexOrigin())
+			if err2 != nil {
+				t.Error(err2.Error())
+			}
+			if len(changes) != 0 {
+				t.Fatalf("Unexpected error: opts isn't equal:\nGot: %#v\nExpect %#v\nDiff: %v\n", resumeItem, testCase.expected, spew.Sdump(changes))
+			}
+		})
+	}
+}
+
+func TestReadResumeItems(t *testing.T) {
+	dir := t.TempDir()
+	resume := map[string]interface{}{
+		".fileguard": "guard",
+		"rec":        map[string]interface{}{},
+		"good.torrent": map[string]interface{}{
+			"path": "/mnt/torrents/good",
+		},
+		"bad.torrent": map[string]interface{}{
+			"path": []interface{}{"/mnt/torrents/bad"},
+		},
+	}
+	encoded, err := bencode.EncodeBytes(resume)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "resume.dat"), encoded, 0644); err != nil {
+		t.Fatal(err)
+	}
+	resumeItems, skipped, err := ReadResumeItems(dir, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(resumeItems) != 1 || resumeItems["good.torrent"] == nil || resumeItems["good.torrent"].Path != "/mnt/torrents/good" {
+		t.Fatalf("Unexpected resume items: %v", spew.Sdump(resumeItems))
+	}
+	var entryError *EntryError
+	if len(skipped) != 1 || !errors.As(skipped["bad.torrent"], &entryError) || entryError.Field != "path" {
+		t.Fatalf("Unexpected skipped entries: %v", spew.Sdump(skipped))
+	}
+
+	if _, _, err = ReadResumeItems(filepath.Join(dir, "notexists"), false); err == nil {
+		t.Fatalf("Test must fail, but it doesn't")
+	}
+}
+
+func TestReadResumeItemsFallback(t *testing.T) {
+	good := map[string]interface{}{
+		"good.torrent": map[string]interface{}{"path": "/mnt/torrents/good"},
+	}
+	fileGuard, err := helpers.FileGuard(good)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withFileGuard := map[string]interface{}{".fileguard": fileGuard}
+	withWrongFileGuard := map[string]interface{}{".fileguard": "0000000000000000000000000000000000000000"}
+	for key, value := range good {
+		withFileGuard[key] = value
+		withWrongFileGuard[key] = value
+	}
+	encoded, _ := bencode.EncodeBytes(withFileGuard)
+	wrongEncoded, _ := bencode.EncodeBytes(withWrongFileGuard)
+	old := map[string]interface{}{
+		"old.torrent": map[string]interface{}{"path": "/mnt/torrents/old"},
+	}
+	oldFileGuard, err := helpers.FileGuard(old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old[".fileguard"] = oldFileGuard
+	oldEncoded, _ := bencode.EncodeBytes(old)
+
+	type FallbackCase struct {
+		name        string
+		mustFail    bool
+		resume      []byte
+		oldResume   []byte
+		expectedKey string
+	}
+	cases := []FallbackCase{
+		{
+			name:        "001 correct fileguard",
+			resume:      encoded,
+			oldResume:   oldEncoded,
+			expectedKey: "good.torrent",
+		},
+		{
+			name:        "002 wrong fileguard with resume.dat.old",
+			resume:      wrongEncoded,
+			oldResume:   oldEncoded,
+			expectedKey: "old.torrent",
+		},
+		{
+			name:        "003 wrong fileguard without resume.dat.old",
+			resume:      wrongEncoded,
+			expectedKey: "good.torrent",
+		},
+		{
+			name:        "004 wrong fileguard with corrupted resume.dat.old",
+			resume:      wrongEncoded,
+			oldResume:   oldEncoded[:len(oldEncoded)/2],
+			expectedKey: "good.torrent",
+		},
+		{
+			name:        "005 truncated resume.dat with resume.dat.old",
+			resume:      encoded[:len(encoded)/2],
+			oldResume:   oldEncoded,
+			expectedKey: "old.torrent",
+		},
+		{
+			name:     "006 truncated resume.dat without resume.dat.old. mustFail",
+			resume:   encoded[:len(encoded)/2],
+			mustFail: true,
+		},
+		{
+			name:      "007 truncated resume.dat and resume.dat.old. mustFail",
+			resume:    encoded[:len(encoded)/2],
+			oldResume: oldEncoded[:len(oldEncoded)/3],
+			mustFail:  true,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "resume.dat"), testCase.resume, 0644); err != nil {
+				t.Fatal(err)
+			}
+			if testCase.oldResume != nil {
+				if err := os.WriteFile(filepath.Join(dir, "resume.dat.old"), testCase.oldResume, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			resumeItems, _, err := ReadResumeItems(dir, false)
+			if err != nil && !testCase.mustFail {
+				t.Fatalf("Unexpected error: %v", err)
+			} else if err == nil && testCase.mustFail {
+				t.Fatalf("Test must fail, but it doesn't")
+			}
+			if !testCase.mustFail && (len(resumeItems) != 1 || resumeItems[testCase.expectedKey] == nil) {
+				t.Fatalf("Unexpected resume items: %v", spew.Sdump(resumeItems))
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package delugeStructures
+
+// https://github.com/deluge-torrent/deluge/blob/develop/deluge/core/torrentmanager.py
+
+import (
+	"fmt"
+
+	"github.com/rumanzo/bt2qbt/pkg/pickle"
+)
+
+// TorrentState entry of torrents.state. Only fields used by migration are decoded
+type TorrentState struct {
+	TorrentId      string
+	Trackers       []string
+	Paused         bool
+	AutoManaged    bool
+	SavePath       string
+	MaxUploadSpeed float64 // KiB/s, -1 if unlimited
+	FilePriorities []int64 // libtorrent priorities, 0 don't download
+	Name           string  // renamed torrent name, since deluge 2.0
+}
+
+// LabelConfig content of label.conf
+type LabelConfig struct {
+	TorrentLabels map[string]string                 `json:"torrent_labels"`
+	Labels        map[string]map[string]interface{} `json:"labels"`
+}
+
+// NewTorrentStates convert unpickled TorrentManagerState to slice of torrent states
+func NewTorrentStates(decoded interface{}) ([]TorrentState, error) {
+	manager, ok := decoded.(*pickle.Object)
+	if !ok {
+		return nil, fmt.Errorf("unexpected torrents.state root %T", decoded)
+	}
+	managerState, ok := manager.State.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected %v state %T", manager.Name, manager.State)
+	}
+	torrents, ok := managerState["torrents"].([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("torrents.state doesn't contain torrents")
+	}
+	var states []TorrentState
+	for _, torrent := range torrents {
+		object, ok := torrent.(*pickle.Object)
+		if !ok {
+			return nil, fmt.Errorf("unexpected torrent state %T", torrent)
+		}
+		fields, ok := object.State.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("unexpected %v state %T", object.Name, object.State)
+		}
+		states = append(states, newTorrentState(fields))
+	}
+	return states, nil
+}
+
+func newTorrentState(fields map[string]interface{}) TorrentState {
+	state := TorrentState{
+		TorrentId:      getString(fields["torrent_id"]),
+		Paused:         getBool(fields["paused"]),
+		AutoManaged:    getBool(fields["auto_managed"]),
+		SavePath:       getString(fields["save_path"]),
+		MaxUploadSpeed: getFloat(fields["max_upload_speed"]),
+		Name:           getString(fields["name"]),
+	}
+	if priorities, ok := fields["file_priorities"].([]interface{}); ok {
+		for _, priority := range priorities {
+			state.FilePriorities = append(state.FilePriorities, int64(getFloat(priority)))
+		}
+	}
+	if trackers, ok := fields["trackers"].([]interface{}); ok {
+		for _, tracker := range trackers {
+			if tracker, ok := tracker.(map[string]interface{}); ok {
+				if url := getString(tracker["url"]); url != "" {
+					state.Trackers = append(state.Trackers, url)
+				}
+			}
+		}
+	}
+	return state
+}
+
+func getString(value interface{}) string {
+	if s, ok := value.(string); ok {
+		return s
+	}
+	return ""
+}
+
+// getBool python 2 pickles can store booleans as integers
+func getBool(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case int64:
+		return v != 0
+	}
+	return false
+}
+
+func getFloat(value interface{}) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	case bool:
+		if v {
+			return 1
+		}
+	}
+	return 0
+}
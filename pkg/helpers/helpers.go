@@ -3,6 +3,10 @@ package helpers
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"github.com/crazytyper/go-cesu8"
 	"github.com/zeebo/bencode"
 	"io"
@@ -45,6 +49,109 @@ func DecodeTorrentFile(path string, decodeTo interface{}) error {
 	return nil
 }
 
+// DecodeTorrentFileRaw decode torrent file to map. Info is kept as raw bytes from file, so it will be encoded
+// exactly as it was and info hash will be the same even for torrents with non-canonical bencode
+func DecodeTorrentFileRaw(path string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	torrent := map[string]interface{}{}
+	if err = bencode.DecodeBytes(data, &torrent); err != nil {
+		return nil, err
+	}
+	info, err := GetRawInfo(data)
+	if err != nil {
+		return nil, err
+	}
+	torrent["info"] = bencode.RawMessage(info)
+	return torrent, nil
+}
+
+// GetRawInfo return raw bytes of info value of bencoded torrent. If info key is duplicated first one is used as libtorrent does
+func GetRawInfo(torrent []byte) ([]byte, error) {
+	if len(torrent) == 0 || torrent[0] != 'd' {
+		return nil, errors.New("torrent isn't bencoded dictionary")
+	}
+	var info []byte
+	pos := 1
+	for pos < len(torrent) && torrent[pos] != 'e' {
+		key, start, err := readBencodeString(torrent, pos)
+		if err != nil {
+			return nil, err
+		}
+		end, err := skipBencodeValue(torrent, start)
+		if err != nil {
+			return nil, err
+		}
+		if key == "info" && info == nil {
+			info = torrent[start:end]
+		}
+		pos = end
+	}
+	if pos >= len(torrent) {
+		return nil, errors.New("unexpected end of torrent")
+	}
+	if info == nil {
+		return nil, errors.New("torrent doesn't contain info")
+	}
+	return info, nil
+}
+
+// readBencodeString read string at pos and return it with position after it
+func readBencodeString(data []byte, pos int) (string, int, error) {
+	colon := bytes.IndexByte(data[pos:], ':')
+	if colon <= 0 {
+		return "", 0, fmt.Errorf("expected string at position %v", pos)
+	}
+	length, err := strconv.Atoi(string(data[pos : pos+colon]))
+	if err != nil || length < 0 {
+		return "", 0, fmt.Errorf("bad string length at position %v", pos)
+	}
+	start := pos + colon + 1
+	if length > len(data)-start {
+		return "", 0, errors.New("unexpected end of torrent")
+	}
+	return string(data[start : start+length]), start + length, nil
+}
+
+// skipBencodeValue return position after value that starts at pos
+func skipBencodeValue(data []byte, pos int) (int, error) {
+	if pos >= len(data) {
+		return 0, errors.New("unexpected end of torrent")
+	}
+	switch c := data[pos]; {
+	case c == 'i':
+		end := bytes.IndexByte(data[pos:], 'e')
+		if end < 0 {
+			return 0, errors.New("unexpected end of torrent")
+		}
+		return pos + end + 1, nil
+	case c == 'l' || c == 'd':
+		pos++
+		for pos < len(data) && data[pos] != 'e' {
+			var err error
+			if c == 'd' {
+				if _, pos, err = readBencodeString(data, pos); err != nil {
+					return 0, err
+				}
+			}
+			if pos, err = skipBencodeValue(data, pos); err != nil {
+				return 0, err
+			}
+		}
+		if pos >= len(data) {
+			return 0, errors.New("unexpected end of torrent")
+		}
+		return pos + 1, nil
+	case c >= '0' && c <= '9':
+		_, end, err := readBencodeString(data, pos)
+		return end, err
+	default:
+		return 0, fmt.Errorf("unexpected symbol %q at position %v", c, pos)
+	}
+}
+
 func EncodeTorrentFile(path string, content interface{}) error {
 	var err error
 	var file *os.File
@@ -76,6 +183,41 @@ func EncodeTorrentFile(path string, content interface{}) error {
 	return nil
 }
 
+// WriteBencodeFile encode content and replace file with it. Content is written to temporary file first, so existing file
+// isn't damaged if encoding or writing fails
+func WriteBencodeFile(path string, content interface{}) error {
+	encoded, err := bencode.EncodeBytes(content)
+	if err != nil {
+		return err
+	}
+	tempPath := path + ".tmp"
+	if err = os.WriteFile(tempPath, encoded, 0666); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	if err = os.Rename(tempPath, path); err != nil {
+		os.Remove(tempPath)
+		return err
+	}
+	return nil
+}
+
+// FileGuard calculate uTorrent .fileguard of resume.dat. It's uppercase SHA-1 of bencoded resume without .fileguard key
+func FileGuard(resume map[string]interface{}) (string, error) {
+	withoutGuard := make(map[string]interface{}, len(resume))
+	for key, value := range resume {
+		if key != ".fileguard" {
+			withoutGuard[key] = value
+		}
+	}
+	encoded, err := bencode.EncodeBytes(withoutGuard)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(encoded)
+	return strings.ToUpper(hex.EncodeToString(sum[:])), nil
+}
+
 func CopyFile(src string, dst string) error {
 	originalFile, err := os.Open(src)
 	if err != nil {
@@ -1,7 +1,11 @@
 package helpers
 
 import (
+	"github.com/zeebo/bencode"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -58,6 +62,60 @@ func TestDecodeTorrentFile(t *testing.T) {
 	}
 }
 
+func TestGetRawInfo(t *testing.T) {
+	type GetRawInfoCase struct {
+		name     string
+		mustFail bool
+		torrent  string
+		expected string
+	}
+	cases := []GetRawInfoCase{
+		{
+			name:     "001 canonical torrent",
+			torrent:  "d8:announce3:url4:infod6:lengthi1e4:name1:aee",
+			expected: "d6:lengthi1e4:name1:ae",
+		},
+		{
+			name:     "002 unsorted keys and leading zeros are kept",
+			torrent:  "d4:infod4:name1:a6:lengthi01ee1:xli1e2:abd1:ai2eeee",
+			expected: "d4:name1:a6:lengthi01ee",
+		},
+		{
+			name:     "003 first of duplicated info",
+			torrent:  "d4:infod1:ai1ee4:infod1:ai2eee",
+			expected: "d1:ai1ee",
+		},
+		{
+			name:     "004 without info. mustFail",
+			torrent:  "d8:announce3:urle",
+			mustFail: true,
+		},
+		{
+			name:     "005 truncated. mustFail",
+			torrent:  "d4:infod6:lengthi1e",
+			mustFail: true,
+		},
+		{
+			name:     "006 not dictionary. mustFail",
+			torrent:  "li1ee",
+			mustFail: true,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			info, err := GetRawInfo([]byte(testCase.torrent))
+			if err != nil && !testCase.mustFail {
+				t.Fatalf("Unexpected error: %v", err)
+			} else if err == nil && testCase.mustFail {
+				t.Fatalf("Test must fail, but it doesn't")
+			}
+			if string(info) != testCase.expected {
+				t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v\n Expect %#v\n", string(info), testCase.expected)
+			}
+		})
+	}
+}
+
 func TestEmojiCesu8(t *testing.T) {
 	cesu8 := "normal_text \xed\xa0\xbc\xed\xb6\x95 normal_text \xed\xa0\xbd\xed\xba\x9c.txt.torrent"
 	utf8 := "normal_text \xf0\x9f\x86\x95 normal_text \xf0\x9f\x9a\x9c.txt.torrent"
@@ -133,3 +191,29 @@ func TestReplaceAllSymbols(t *testing.T) {
 		})
 	}
 }
+
+func TestWriteBencodeFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "resume.dat")
+	// content bigger than write buffer and existing file bigger than content
+	if err := os.WriteFile(path, []byte(strings.Repeat("x", 20000)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	content := map[string]interface{}{"key": strings.Repeat("a", 10000)}
+	if err := WriteBencodeFile(path, content); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	written, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := bencode.EncodeBytes(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(written) != string(expected) {
+		t.Fatalf("Unexpected file of %v bytes, expect %v bytes", len(written), len(expected))
+	}
+	if _, err = os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Fatalf("Temporary file must be removed")
+	}
+}
@@ -0,0 +1,153 @@
+package partFiles
+
+/*
+uTorrent store pieces of not downloaded files (that intersect with downloaded files) in ~uTorrentPartFile_<HASH>.dat
+Header of this file is array of little endian uint32, one per piece of torrent. Zero means that piece isn't stored,
+otherwise piece stored in slot (value - 1). Slots follow header, every slot has piece length size.
+
+libtorrent store the same pieces in .<infohash>.parts in save path. Header is big endian uint32 number of pieces,
+big endian uint32 piece length and array of big endian uint32 slot indexes (0xffffffff if piece isn't stored).
+Header rounded up to 1024 bytes, slots follow header. https://github.com/arvidn/libtorrent/blob/RC_2_0/src/part_file.cpp
+*/
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const UTorrentPartFilePattern = "~uTorrentPartFile_*.dat"
+
+const unallocatedSlot = 0xffffffff
+
+// FindUTorrentPartFile return path of partfile in directory with torrent data or empty string if it doesn't exist
+func FindUTorrentPartFile(dir string, numPieces int64, pieceLength int64) string {
+	matches, err := filepath.Glob(filepath.Join(dir, UTorrentPartFilePattern))
+	if err != nil {
+		return ""
+	}
+	sort.Strings(matches)
+	// directory can contain partfiles of several torrents, so check that header fit this torrent
+	for _, match := range matches {
+		if slots, err := readUTorrentHeader(match, numPieces, pieceLength); err == nil && len(slots) > 0 {
+			return match
+		}
+	}
+	return ""
+}
+
+// LibtorrentPartFileName return file name that libtorrent use for partfile
+func LibtorrentPartFileName(hash string) string {
+	return "." + hash + ".parts"
+}
+
+// readUTorrentHeader return map of piece index to slot
+func readUTorrentHeader(path string, numPieces int64, pieceLength int64) (map[int64]int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	stat, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	headerSize := numPieces * 4
+	if stat.Size() < headerSize {
+		return nil, fmt.Errorf("partfile %v is too small for torrent with %v pieces", path, numPieces)
+	}
+	header := make([]byte, headerSize)
+	if _, err = io.ReadFull(file, header); err != nil {
+		return nil, err
+	}
+	maxSlots := (stat.Size() - headerSize + pieceLength - 1) / pieceLength
+	slots := map[int64]int64{}
+	for piece := int64(0); piece < numPieces; piece++ {
+		value := int64(binary.LittleEndian.Uint32(header[piece*4:]))
+		if value == 0 {
+			continue
+		}
+		if value > maxSlots {
+			return nil, fmt.Errorf("partfile %v has slot %v out of file", path, value)
+		}
+		slots[piece] = value - 1
+	}
+	return slots, nil
+}
+
+// ConvertUTorrentPartFile write libtorrent partfile dst with pieces from uTorrent partfile src.
+// Return indexes of converted pieces
+func ConvertUTorrentPartFile(src string, dst string, numPieces int64, pieceLength int64) ([]int64, error) {
+	slots, err := readUTorrentHeader(src, numPieces, pieceLength)
+	if err != nil {
+		return nil, err
+	}
+	pieces := make([]int64, 0, len(slots))
+	for piece := range slots {
+		pieces = append(pieces, piece)
+	}
+	sort.Slice(pieces, func(i, j int) bool { return pieces[i] < pieces[j] })
+
+	srcFile, err := os.Open(src)
+	if err != nil {
+		return nil, err
+	}
+	defer srcFile.Close()
+	// failed conversion mustn't leave partially written partfile in save path
+	dstFile, err := os.Create(dst + ".tmp")
+	if err != nil {
+		return nil, err
+	}
+	if err = writeLibtorrentPartFile(dstFile, srcFile, slots, pieces, numPieces, pieceLength); err == nil {
+		err = dstFile.Sync()
+	}
+	if closeErr := dstFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(dstFile.Name(), dst)
+	}
+	if err != nil {
+		os.Remove(dstFile.Name())
+		return nil, err
+	}
+	return pieces, nil
+}
+
+func writeLibtorrentPartFile(dstFile *os.File, srcFile *os.File, slots map[int64]int64, pieces []int64, numPieces int64, pieceLength int64) error {
+	dstHeaderSize := (numPieces*4 + 8 + 1023) &^ 1023
+	header := make([]byte, dstHeaderSize)
+	binary.BigEndian.PutUint32(header[0:], uint32(numPieces))
+	binary.BigEndian.PutUint32(header[4:], uint32(pieceLength))
+	for piece := int64(0); piece < numPieces; piece++ {
+		binary.BigEndian.PutUint32(header[8+piece*4:], unallocatedSlot)
+	}
+	for newSlot, piece := range pieces {
+		binary.BigEndian.PutUint32(header[8+piece*4:], uint32(newSlot))
+	}
+	if _, err := dstFile.Write(header); err != nil {
+		return err
+	}
+
+	srcHeaderSize := numPieces * 4
+	buf := make([]byte, pieceLength)
+	for _, piece := range pieces {
+		// last slot may be shorter than piece length, rest of it will be filled with zeros
+		for i := range buf {
+			buf[i] = 0
+		}
+		n, err := srcFile.ReadAt(buf, srcHeaderSize+slots[piece]*pieceLength)
+		if err != nil && err != io.EOF {
+			return err
+		} else if n == 0 {
+			return fmt.Errorf("partfile %v doesn't contain piece %v", srcFile.Name(), piece)
+		}
+		if _, err = dstFile.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
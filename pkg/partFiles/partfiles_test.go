@@ -0,0 +1,85 @@
+package partFiles
+
+import (
+	"bytes"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConvertUTorrentPartFile(t *testing.T) {
+	dir := t.TempDir()
+	numPieces, pieceLength := int64(4), int64(8)
+
+	// piece 3 stored in first slot, piece 1 in second slot (shorter, as last piece of file)
+	var uTorrentPartFile []byte
+	for _, value := range []uint32{0, 2, 0, 1} {
+		uTorrentPartFile = binary.LittleEndian.AppendUint32(uTorrentPartFile, value)
+	}
+	uTorrentPartFile = append(uTorrentPartFile, bytes.Repeat([]byte{3}, 8)...)
+	uTorrentPartFile = append(uTorrentPartFile, bytes.Repeat([]byte{1}, 5)...)
+	src := filepath.Join(dir, "~uTorrentPartFile_1A2B3C.dat")
+	if err := os.WriteFile(src, uTorrentPartFile, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// partfile of another torrent with bigger header must be skipped
+	if err := os.WriteFile(filepath.Join(dir, "~uTorrentPartFile_0.dat"), []byte{1, 0, 0, 0}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if found := FindUTorrentPartFile(dir, numPieces, pieceLength); found != src {
+		t.Fatalf("Unexpected partfile. Got %v, expect %v", found, src)
+	}
+
+	dst := filepath.Join(dir, LibtorrentPartFileName("0123456789abcdef0123456789abcdef01234567"))
+	pieces, err := ConvertUTorrentPartFile(src, dst, numPieces, pieceLength)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(pieces, []int64{1, 3}) {
+		t.Fatalf("Unexpected converted pieces: %v", pieces)
+	}
+
+	libtorrentPartFile, err := os.ReadFile(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(libtorrentPartFile) != 1024+2*8 {
+		t.Fatalf("Unexpected libtorrent partfile size %v", len(libtorrentPartFile))
+	}
+	expectedHeader := []uint32{4, 8, 0xffffffff, 0, 0xffffffff, 1}
+	for i, expected := range expectedHeader {
+		if value := binary.BigEndian.Uint32(libtorrentPartFile[i*4:]); value != expected {
+			t.Fatalf("Unexpected header value %v at %v, expect %v", value, i, expected)
+		}
+	}
+	expectedData := append(append(bytes.Repeat([]byte{1}, 5), 0, 0, 0), bytes.Repeat([]byte{3}, 8)...)
+	if !bytes.Equal(libtorrentPartFile[1024:], expectedData) {
+		t.Fatalf("Unexpected libtorrent partfile data:\nGot: %v\nExpect: %v", libtorrentPartFile[1024:], expectedData)
+	}
+}
+
+func TestConvertUTorrentPartFileFailed(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "~uTorrentPartFile_1A2B3C.dat")
+	if err := os.WriteFile(src, []byte{1, 0, 0, 0, 1, 1, 1, 1}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	// destination can't be replaced, so converted partfile can't be moved to it
+	dst := filepath.Join(dir, LibtorrentPartFileName("0123456789abcdef0123456789abcdef01234567"))
+	if err := os.MkdirAll(filepath.Join(dst, "nested"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ConvertUTorrentPartFile(src, dst, 1, 4); err == nil {
+		t.Fatalf("Test must fail, but it doesn't")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Unexpected files after failed conversion: %v", entries)
+	}
+}
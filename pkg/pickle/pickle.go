@@ -0,0 +1,657 @@
+package pickle
+
+/*
+Minimal python pickle decoder. It supports protocols 0-5 opcodes that used for plain data and class instances,
+but doesn't execute anything. Instances are decoded as *Object with class name, constructor arguments and state.
+Lists and tuples are decoded as []interface{}, dicts as map[string]interface{} (not string keys are formatted),
+str and unicode as string, int and long as int64 (or *big.Int if it doesn't fit), bool as bool and None as nil.
+https://github.com/python/cpython/blob/main/Lib/pickletools.py
+*/
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Object instance of python class
+type Object struct {
+	Module string
+	Name   string
+	Args   []interface{}
+	State  interface{}
+}
+
+// Class python class reference, result of GLOBAL opcodes
+type Class struct {
+	Module string
+	Name   string
+}
+
+type mark struct{}
+
+// list used while decoding, because lists can be appended after they were memoized
+type list struct {
+	items []interface{}
+}
+
+type decoder struct {
+	src   *bytes.Reader
+	r     *bufio.Reader
+	stack []interface{}
+	memo  map[int64]interface{}
+}
+
+func DecodeFile(path string) (interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Decode(data)
+}
+
+func Decode(data []byte) (value interface{}, err error) {
+	// corrupted pickle mustn't crash migration
+	defer func() {
+		if r := recover(); r != nil {
+			value, err = nil, fmt.Errorf("corrupted pickle: %v", r)
+		}
+	}()
+	src := bytes.NewReader(data)
+	d := &decoder{src: src, r: bufio.NewReader(src), memo: map[int64]interface{}{}}
+	value, err = d.decode()
+	if err != nil {
+		return nil, err
+	}
+	return finalize(value), nil
+}
+
+func (d *decoder) push(value interface{}) {
+	d.stack = append(d.stack, value)
+}
+
+func (d *decoder) pop() (interface{}, error) {
+	if len(d.stack) == 0 {
+		return nil, errors.New("pickle stack underflow")
+	}
+	value := d.stack[len(d.stack)-1]
+	d.stack = d.stack[:len(d.stack)-1]
+	return value, nil
+}
+
+func (d *decoder) top() (interface{}, error) {
+	if len(d.stack) == 0 {
+		return nil, errors.New("pickle stack underflow")
+	}
+	return d.stack[len(d.stack)-1], nil
+}
+
+// popMark return all items after last mark
+func (d *decoder) popMark() ([]interface{}, error) {
+	for i := len(d.stack) - 1; i >= 0; i-- {
+		if _, ok := d.stack[i].(mark); ok {
+			items := append([]interface{}{}, d.stack[i+1:]...)
+			d.stack = d.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, errors.New("pickle mark not found")
+}
+
+func (d *decoder) readLine() (string, error) {
+	line, err := d.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(line, "\n"), "\r"), nil
+}
+
+// readBytes read n bytes. Length is checked against rest of input, so corrupted length can't cause huge allocation
+func (d *decoder) readBytes(n uint64) ([]byte, error) {
+	if rest := uint64(d.src.Len() + d.r.Buffered()); n > rest {
+		return nil, fmt.Errorf("pickle length %v exceeds rest of input %v", n, rest)
+	}
+	buf := make([]byte, n)
+	_, err := io.ReadFull(d.r, buf)
+	return buf, err
+}
+
+func (d *decoder) readUint(size int) (uint64, error) {
+	buf, err := d.readBytes(uint64(size))
+	if err != nil {
+		return 0, err
+	}
+	switch size {
+	case 1:
+		return uint64(buf[0]), nil
+	case 2:
+		return uint64(binary.LittleEndian.Uint16(buf)), nil
+	case 4:
+		return uint64(binary.LittleEndian.Uint32(buf)), nil
+	default:
+		return binary.LittleEndian.Uint64(buf), nil
+	}
+}
+
+func (d *decoder) decode() (interface{}, error) {
+	for {
+		op, err := d.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case 0x80: // PROTO
+			if _, err = d.r.ReadByte(); err != nil {
+				return nil, err
+			}
+		case 0x95: // FRAME
+			if _, err = d.readUint(8); err != nil {
+				return nil, err
+			}
+		case '.': // STOP
+			return d.pop()
+		case '(': // MARK
+			d.push(mark{})
+		case '0': // POP
+			if _, err = d.pop(); err != nil {
+				return nil, err
+			}
+		case '1': // POP_MARK
+			if _, err = d.popMark(); err != nil {
+				return nil, err
+			}
+		case '2': // DUP
+			value, err := d.top()
+			if err != nil {
+				return nil, err
+			}
+			d.push(value)
+		case 'N': // NONE
+			d.push(nil)
+		case 0x88: // NEWTRUE
+			d.push(true)
+		case 0x89: // NEWFALSE
+			d.push(false)
+		case 'I': // INT
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			switch line {
+			case "00":
+				d.push(false)
+			case "01":
+				d.push(true)
+			default:
+				value, err := strconv.ParseInt(line, 10, 64)
+				if err != nil {
+					return nil, err
+				}
+				d.push(value)
+			}
+		case 'L': // LONG
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			value, ok := new(big.Int).SetString(strings.TrimSuffix(line, "L"), 10)
+			if !ok {
+				return nil, fmt.Errorf("pickle bad long %q", line)
+			}
+			d.push(normalizeInt(value))
+		case 'J': // BININT
+			value, err := d.readUint(4)
+			if err != nil {
+				return nil, err
+			}
+			d.push(int64(int32(value)))
+		case 'K': // BININT1
+			value, err := d.readUint(1)
+			if err != nil {
+				return nil, err
+			}
+			d.push(int64(value))
+		case 'M': // BININT2
+			value, err := d.readUint(2)
+			if err != nil {
+				return nil, err
+			}
+			d.push(int64(value))
+		case 0x8a, 0x8b: // LONG1, LONG4
+			size := 1
+			if op == 0x8b {
+				size = 4
+			}
+			n, err := d.readUint(size)
+			if err != nil {
+				return nil, err
+			}
+			buf, err := d.readBytes(n)
+			if err != nil {
+				return nil, err
+			}
+			d.push(decodeLong(buf))
+		case 'F': // FLOAT
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			value, err := strconv.ParseFloat(line, 64)
+			if err != nil {
+				return nil, err
+			}
+			d.push(value)
+		case 'G': // BINFLOAT
+			buf, err := d.readBytes(8)
+			if err != nil {
+				return nil, err
+			}
+			d.push(math.Float64frombits(binary.BigEndian.Uint64(buf)))
+		case 'S': // STRING
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			value, err := unquote(line)
+			if err != nil {
+				return nil, err
+			}
+			d.push(value)
+		case 'V': // UNICODE
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			d.push(decodeRawUnicodeEscape(line))
+		case 'T', 'U', 'X', 0x8c, 0x8d, 'B', 'C', 0x8e: // BINSTRING, SHORT_BINSTRING, BINUNICODE, SHORT_BINUNICODE, BINUNICODE8, BINBYTES, SHORT_BINBYTES, BINBYTES8
+			size := 4
+			switch op {
+			case 'U', 0x8c, 'C':
+				size = 1
+			case 0x8d, 0x8e:
+				size = 8
+			}
+			n, err := d.readUint(size)
+			if err != nil {
+				return nil, err
+			}
+			buf, err := d.readBytes(n)
+			if err != nil {
+				return nil, err
+			}
+			d.push(string(buf))
+		case ']': // EMPTY_LIST
+			d.push(&list{})
+		case 'l': // LIST
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			d.push(&list{items: items})
+		case 'a': // APPEND
+			value, err := d.pop()
+			if err != nil {
+				return nil, err
+			}
+			if err = d.appendItems([]interface{}{value}); err != nil {
+				return nil, err
+			}
+		case 'e': // APPENDS
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			if err = d.appendItems(items); err != nil {
+				return nil, err
+			}
+		case ')': // EMPTY_TUPLE
+			d.push([]interface{}{})
+		case 't': // TUPLE
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			d.push(items)
+		case 0x85, 0x86, 0x87: // TUPLE1, TUPLE2, TUPLE3
+			n := int(op-0x85) + 1
+			if len(d.stack) < n {
+				return nil, errors.New("pickle stack underflow")
+			}
+			items := append([]interface{}{}, d.stack[len(d.stack)-n:]...)
+			d.stack = d.stack[:len(d.stack)-n]
+			d.push(items)
+		case '}': // EMPTY_DICT
+			d.push(map[string]interface{}{})
+		case 'd': // DICT
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			dict := map[string]interface{}{}
+			if err = setItems(dict, items); err != nil {
+				return nil, err
+			}
+			d.push(dict)
+		case 's', 'u': // SETITEM, SETITEMS
+			var items []interface{}
+			if op == 's' {
+				if len(d.stack) < 2 {
+					return nil, errors.New("pickle stack underflow")
+				}
+				items = append([]interface{}{}, d.stack[len(d.stack)-2:]...)
+				d.stack = d.stack[:len(d.stack)-2]
+			} else if items, err = d.popMark(); err != nil {
+				return nil, err
+			}
+			top, err := d.top()
+			if err != nil {
+				return nil, err
+			}
+			dict, ok := top.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("pickle can't set item of %T", top)
+			}
+			if err = setItems(dict, items); err != nil {
+				return nil, err
+			}
+		case 0x8f: // EMPTY_SET
+			d.push(&list{})
+		case 0x90: // ADDITEMS
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			if err = d.appendItems(items); err != nil {
+				return nil, err
+			}
+		case 0x91: // FROZENSET
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			d.push(items)
+		case 'c', 'i': // GLOBAL, INST
+			module, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			name, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			if op == 'c' {
+				d.push(&Class{Module: module, Name: name})
+			} else {
+				args, err := d.popMark()
+				if err != nil {
+					return nil, err
+				}
+				d.push(&Object{Module: module, Name: name, Args: args})
+			}
+		case 0x93: // STACK_GLOBAL
+			name, err := d.pop()
+			if err != nil {
+				return nil, err
+			}
+			module, err := d.pop()
+			if err != nil {
+				return nil, err
+			}
+			d.push(&Class{Module: fmt.Sprint(module), Name: fmt.Sprint(name)})
+		case 'o': // OBJ
+			items, err := d.popMark()
+			if err != nil {
+				return nil, err
+			}
+			if len(items) == 0 {
+				return nil, errors.New("pickle OBJ without class")
+			}
+			d.push(newObject(items[0], items[1:]))
+		case 0x81, 'R': // NEWOBJ, REDUCE
+			args, err := d.pop()
+			if err != nil {
+				return nil, err
+			}
+			class, err := d.pop()
+			if err != nil {
+				return nil, err
+			}
+			argsList, _ := args.([]interface{})
+			d.push(newObject(class, argsList))
+		case 'b': // BUILD
+			state, err := d.pop()
+			if err != nil {
+				return nil, err
+			}
+			top, err := d.top()
+			if err != nil {
+				return nil, err
+			}
+			if object, ok := top.(*Object); ok {
+				object.State = state
+			}
+		case 'p': // PUT
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			index, err := strconv.ParseInt(line, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			if d.memo[index], err = d.top(); err != nil {
+				return nil, err
+			}
+		case 'q', 'r': // BINPUT, LONG_BINPUT
+			size := 1
+			if op == 'r' {
+				size = 4
+			}
+			index, err := d.readUint(size)
+			if err != nil {
+				return nil, err
+			}
+			if d.memo[int64(index)], err = d.top(); err != nil {
+				return nil, err
+			}
+		case 0x94: // MEMOIZE
+			value, err := d.top()
+			if err != nil {
+				return nil, err
+			}
+			d.memo[int64(len(d.memo))] = value
+		case 'g': // GET
+			line, err := d.readLine()
+			if err != nil {
+				return nil, err
+			}
+			index, err := strconv.ParseInt(line, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			d.push(d.memo[index])
+		case 'h', 'j': // BINGET, LONG_BINGET
+			size := 1
+			if op == 'j' {
+				size = 4
+			}
+			index, err := d.readUint(size)
+			if err != nil {
+				return nil, err
+			}
+			d.push(d.memo[int64(index)])
+		default:
+			return nil, fmt.Errorf("pickle unsupported opcode 0x%x", op)
+		}
+	}
+}
+
+func (d *decoder) appendItems(items []interface{}) error {
+	top, err := d.top()
+	if err != nil {
+		return err
+	}
+	l, ok := top.(*list)
+	if !ok {
+		return fmt.Errorf("pickle can't append to %T", top)
+	}
+	l.items = append(l.items, items...)
+	return nil
+}
+
+func newObject(class interface{}, args []interface{}) *Object {
+	object := &Object{}
+	if len(args) > 0 {
+		object.Args = args
+	}
+	c, ok := class.(*Class)
+	// protocols 0 and 1 create instances with copy_reg._reconstructor(cls, base, state)
+	if ok && c.Name == "_reconstructor" && (c.Module == "copy_reg" || c.Module == "copyreg") && len(args) > 0 {
+		c, ok = args[0].(*Class)
+		object.Args = nil
+	}
+	if ok {
+		object.Module = c.Module
+		object.Name = c.Name
+	}
+	return object
+}
+
+func setItems(dict map[string]interface{}, items []interface{}) error {
+	if len(items)%2 != 0 {
+		return errors.New("pickle odd number of dict items")
+	}
+	for i := 0; i < len(items); i += 2 {
+		key, ok := items[i].(string)
+		if !ok {
+			key = fmt.Sprint(items[i])
+		}
+		dict[key] = items[i+1]
+	}
+	return nil
+}
+
+// finalize replace service list type with slices
+func finalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case *list:
+		items := make([]interface{}, len(v.items))
+		for i, item := range v.items {
+			items[i] = finalize(item)
+		}
+		return items
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = finalize(item)
+		}
+		return items
+	case map[string]interface{}:
+		for key, item := range v {
+			v[key] = finalize(item)
+		}
+		return v
+	case *Object:
+		for i, arg := range v.Args {
+			v.Args[i] = finalize(arg)
+		}
+		v.State = finalize(v.State)
+		return v
+	}
+	return value
+}
+
+// decodeLong decode little endian two's complement integer
+func decodeLong(buf []byte) interface{} {
+	if len(buf) == 0 {
+		return int64(0)
+	}
+	bigEndian := make([]byte, len(buf))
+	for i, b := range buf {
+		bigEndian[len(buf)-1-i] = b
+	}
+	value := new(big.Int).SetBytes(bigEndian)
+	if buf[len(buf)-1]&0x80 != 0 {
+		value.Sub(value, new(big.Int).Lsh(big.NewInt(1), uint(len(buf)*8)))
+	}
+	return normalizeInt(value)
+}
+
+func normalizeInt(value *big.Int) interface{} {
+	if value.IsInt64() {
+		return value.Int64()
+	}
+	return value
+}
+
+// unquote decode python 2 repr of str
+func unquote(s string) (string, error) {
+	if len(s) < 2 || (s[0] != '\'' && s[0] != '"') || s[len(s)-1] != s[0] {
+		return "", fmt.Errorf("pickle bad string %q", s)
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 't':
+			b.WriteByte('\t')
+		case 'x':
+			if i+2 >= len(s) {
+				return "", fmt.Errorf("pickle bad escape in string %q", s)
+			}
+			value, err := strconv.ParseUint(s[i+1:i+3], 16, 8)
+			if err != nil {
+				return "", err
+			}
+			b.WriteByte(byte(value))
+			i += 2
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
+
+// decodeRawUnicodeEscape decode python raw-unicode-escape, only \u and \U are escaped, other bytes are latin-1
+func decodeRawUnicodeEscape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && (s[i+1] == 'u' || s[i+1] == 'U') {
+			size := 4
+			if s[i+1] == 'U' {
+				size = 8
+			}
+			if i+2+size <= len(s) {
+				if value, err := strconv.ParseUint(s[i+2:i+2+size], 16, 32); err == nil {
+					b.WriteRune(rune(value))
+					i += 1 + size
+					continue
+				}
+			}
+		}
+		if s[i] < utf8.RuneSelf {
+			b.WriteByte(s[i])
+		} else {
+			b.WriteRune(rune(s[i]))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,115 @@
+package pickle
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/r3labs/diff/v2"
+)
+
+func TestDecode(t *testing.T) {
+	type DecodeCase struct {
+		name     string
+		data     string
+		expected interface{}
+		mustFail bool
+	}
+	big70, _ := new(big.Int).SetString("1180591620717411303424", 10)
+	torrentState := func(savePath string) *Object {
+		return &Object{
+			Module: "deluge.core.torrentmanager",
+			Name:   "TorrentManagerState",
+			State: map[string]interface{}{
+				"torrents": []interface{}{
+					&Object{
+						Module: "deluge.core.torrentmanager",
+						Name:   "TorrentState",
+						State: map[string]interface{}{
+							"torrent_id":       "abc",
+							"paused":           true,
+							"max_upload_speed": -1.0,
+							"file_priorities":  []interface{}{int64(0), int64(4), int64(7)},
+							"trackers":         []interface{}{map[string]interface{}{"url": "udp://t/a", "tier": int64(0)}},
+							"save_path":        savePath,
+							"queue":            int64(-1),
+							"big":              big70,
+						},
+					},
+				},
+			},
+		}
+	}
+	cases := []DecodeCase{
+		{
+			name:     "001 protocol 0 instances",
+			data:     "ccopy_reg\n_reconstructor\np0\n(cdeluge.core.torrentmanager\nTorrentManagerState\np1\nc__builtin__\nobject\np2\nNtp3\nRp4\n(dp5\nVtorrents\np6\n(lp7\ng0\n(cdeluge.core.torrentmanager\nTorrentState\np8\ng2\nNtp9\nRp10\n(dp11\nVtorrent_id\np12\nVabc\np13\nsVpaused\np14\nI01\nsVmax_upload_speed\np15\nF-1.0\nsVfile_priorities\np16\n(I0\nI4\nI7\ntp17\nsVtrackers\np18\n(lp19\n(dp20\nVurl\np21\nVudp://t/a\np22\nsVtier\np23\nI0\nsasVsave_path\np24\nV/d/\xfc\np25\nsVqueue\np26\nI-1\nsVbig\np27\nL1180591620717411303424L\nsbasb.",
+			expected: torrentState("/d/ü"),
+		},
+		{
+			name:     "002 protocol 2 instances",
+			data:     "\x80\x02cdeluge.core.torrentmanager\nTorrentManagerState\nq\x00)\x81q\x01}q\x02X\x08\x00\x00\x00torrentsq\x03]q\x04cdeluge.core.torrentmanager\nTorrentState\nq\x05)\x81q\x06}q\x07(X\n\x00\x00\x00torrent_idq\x08X\x03\x00\x00\x00abcq\tX\x06\x00\x00\x00pausedq\n\x88X\x10\x00\x00\x00max_upload_speedq\x0bG\xbf\xf0\x00\x00\x00\x00\x00\x00X\x0f\x00\x00\x00file_prioritiesq\x0cK\x00K\x04K\x07\x87q\rX\x08\x00\x00\x00trackersq\x0e]q\x0f}q\x10(X\x03\x00\x00\x00urlq\x11X\t\x00\x00\x00udp://t/aq\x12X\x04\x00\x00\x00tierq\x13K\x00uaX\t\x00\x00\x00save_pathq\x14X\x05\x00\x00\x00/d/\xc3\xbcq\x15X\x05\x00\x00\x00queueq\x16J\xff\xff\xff\xffX\x03\x00\x00\x00bigq\x17\x8a\t\x00\x00\x00\x00\x00\x00\x00\x00@ubasb.",
+			expected: torrentState("/d/ü"),
+		},
+		{
+			name:     "003 python 2 str with escapes",
+			data:     "(lp0\nS'it\\'s \\xc3\\xbc'\np1\naI00\na.",
+			expected: []interface{}{"it's ü", false},
+		},
+		{
+			name:     "004 truncated pickle",
+			data:     "(lp0\nS'abc'\n",
+			mustFail: true,
+		},
+		{
+			name:     "005 unsupported opcode",
+			data:     "\x80\x02\xff.",
+			mustFail: true,
+		},
+		{
+			name:     "006 oversized BINUNICODE length",
+			data:     "\x80\x02X\xff\xff\xff\xffabc.",
+			mustFail: true,
+		},
+		{
+			name:     "007 oversized BINBYTES8 length",
+			data:     "\x80\x04\x8e\xff\xff\xff\xff\xff\xff\xff\x7fabc.",
+			mustFail: true,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			decoded, err := Decode([]byte(testCase.data))
+			if err != nil && !testCase.mustFail {
+				t.Fatalf("Unexpected error: %v", err)
+			} else if err == nil && testCase.mustFail {
+				t.Fatalf("Test must fail, but it doesn't")
+			}
+			if testCase.mustFail {
+				return
+			}
+			if !reflect.DeepEqual(decoded, testCase.expected) {
+				changes, _ := diff.Diff(decoded, testCase.expected, diff.DiscardComplexOrigin())
+				t.Fatalf("Unexpected error: decoded value isn't equal:\n Got: %#v \n Expect %#v \n Diff: %v\n", spew.Sdump(decoded), spew.Sdump(testCase.expected), spew.Sdump(changes))
+			}
+		})
+	}
+}
+
+// TestDecodeCorrupted decode truncated and damaged pickles, decoder must return error instead of panic or huge allocation
+func TestDecodeCorrupted(t *testing.T) {
+	data := "\x80\x02cdeluge.core.torrentmanager\nTorrentManagerState\nq\x00)\x81q\x01}q\x02X\x08\x00\x00\x00torrentsq\x03]q\x04" +
+		"cdeluge.core.torrentmanager\nTorrentState\nq\x05)\x81q\x06}q\x07(X\n\x00\x00\x00torrent_idq\x08X\x03\x00\x00\x00abcq\t" +
+		"X\x05\x00\x00\x00queueq\x16J\xff\xff\xff\xff\x8a\t\x00\x00\x00\x00\x00\x00\x00\x00@ubasb."
+	for i := 0; i < len(data); i++ {
+		if _, err := Decode([]byte(data[:i])); err == nil {
+			t.Fatalf("Truncated pickle with length %v must fail, but it doesn't", i)
+		}
+		for _, b := range []byte{0x00, 0x7f, 0xff} {
+			damaged := []byte(data)
+			damaged[i] = b
+			Decode(damaged)
+		}
+	}
+}
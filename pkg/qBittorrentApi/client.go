@@ -0,0 +1,192 @@
+package qBittorrentApi
+
+// https://github.com/qbittorrent/qBittorrent/wiki/WebUI-API-(qBittorrent-4.1)
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AddParams parameters of torrents/add request
+type AddParams struct {
+	Savepath      string
+	Category      string
+	Tags          []string
+	Paused        bool
+	SkipChecking  bool
+	ContentLayout string // Original, Subfolder or NoSubfolder
+	Rename        string
+}
+
+type Client struct {
+	BaseUrl    string
+	HttpClient *http.Client
+	Retries    int           // retries of file requests while torrent is being added
+	RetryDelay time.Duration // delay between retries
+}
+
+func NewClient(baseUrl string) (*Client, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Client{
+		BaseUrl:    strings.TrimSuffix(baseUrl, "/"),
+		HttpClient: &http.Client{Jar: jar, Timeout: time.Minute},
+		Retries:    10,
+		RetryDelay: 500 * time.Millisecond,
+	}, nil
+}
+
+// Login authenticate client, session cookie is stored in cookie jar
+func (c *Client) Login(username, password string) error {
+	body, err := c.post("auth/login", url.Values{"username": {username}, "password": {password}})
+	if err != nil {
+		return err
+	}
+	if body != "Ok." {
+		return fmt.Errorf("qBittorrent WebUI login failed: %v", body)
+	}
+	return nil
+}
+
+// AddTorrent add torrent file. Magnet link is used if torrent file is empty
+func (c *Client) AddTorrent(params *AddParams, torrentName string, torrent []byte, magnet string) error {
+	buf := &bytes.Buffer{}
+	writer := multipart.NewWriter(buf)
+	if len(torrent) > 0 {
+		part, err := writer.CreateFormFile("torrents", torrentName)
+		if err != nil {
+			return err
+		}
+		if _, err = part.Write(torrent); err != nil {
+			return err
+		}
+	} else if err := writer.WriteField("urls", magnet); err != nil {
+		return err
+	}
+	fields := [][2]string{
+		{"savepath", params.Savepath},
+		{"category", params.Category},
+		{"tags", strings.Join(params.Tags, ",")},
+		{"paused", strconv.FormatBool(params.Paused)},
+		{"stopped", strconv.FormatBool(params.Paused)}, // since qBittorrent 5.0
+		{"skip_checking", strconv.FormatBool(params.SkipChecking)},
+		{"contentLayout", params.ContentLayout},
+		{"rename", params.Rename},
+	}
+	for _, field := range fields {
+		if field[1] == "" {
+			continue
+		}
+		if err := writer.WriteField(field[0], field[1]); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, c.apiUrl("torrents/add"), buf)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", writer.FormDataContentType())
+	body, err := c.do(request)
+	if err != nil {
+		return err
+	}
+	if body == "Fails." {
+		return fmt.Errorf("qBittorrent WebUI can't add torrent %v", torrentName)
+	}
+	return nil
+}
+
+// SetFilePriority set priority of files with ids. Priorities are 0 do not download, 1 normal, 6 high, 7 maximal
+func (c *Client) SetFilePriority(hash string, ids []int, priority int64) error {
+	stringIds := make([]string, 0, len(ids))
+	for _, id := range ids {
+		stringIds = append(stringIds, strconv.Itoa(id))
+	}
+	return c.retry(func() error {
+		_, err := c.post("torrents/filePrio", url.Values{
+			"hash":     {hash},
+			"id":       {strings.Join(stringIds, "|")},
+			"priority": {strconv.FormatInt(priority, 10)},
+		})
+		return err
+	})
+}
+
+// RenameFile rename file of torrent. Paths are relative to save path and contain torrent root folder if it exists
+func (c *Client) RenameFile(hash, oldPath, newPath string) error {
+	return c.retry(func() error {
+		_, err := c.post("torrents/renameFile", url.Values{"hash": {hash}, "oldPath": {oldPath}, "newPath": {newPath}})
+		return err
+	})
+}
+
+// retry qBittorrent adds torrents asynchronously, so it can respond not found or conflict for some time after adding
+func (c *Client) retry(request func() error) error {
+	var err error
+	for i := 0; i <= c.Retries; i++ {
+		if err = request(); err == nil {
+			return nil
+		}
+		if statusErr, ok := err.(*StatusError); !ok || (statusErr.Code != http.StatusNotFound && statusErr.Code != http.StatusConflict) {
+			return err
+		}
+		time.Sleep(c.RetryDelay)
+	}
+	return err
+}
+
+// StatusError unexpected http status of api response
+type StatusError struct {
+	Method string
+	Code   int
+	Body   string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("qBittorrent WebUI %v responded with status %v: %v", e.Method, e.Code, e.Body)
+}
+
+func (c *Client) apiUrl(method string) string {
+	return c.BaseUrl + "/api/v2/" + method
+}
+
+func (c *Client) post(method string, values url.Values) (string, error) {
+	request, err := http.NewRequest(http.MethodPost, c.apiUrl(method), strings.NewReader(values.Encode()))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(request)
+}
+
+func (c *Client) do(request *http.Request) (string, error) {
+	// qBittorrent checks referer for CSRF protection
+	request.Header.Set("Referer", c.BaseUrl)
+	response, err := c.HttpClient.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", &StatusError{Method: strings.TrimPrefix(request.URL.Path, "/api/v2/"), Code: response.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return strings.TrimSpace(string(body)), nil
+}
@@ -0,0 +1,100 @@
+package qBittorrentApi
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// fakeServer emulate qBittorrent WebUI, it requires session cookie and responds conflict on first file request
+func fakeServer(t *testing.T, requests *[]string) *httptest.Server {
+	conflicts := map[string]bool{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v2/auth/login" {
+			if r.FormValue("username") != "admin" || r.FormValue("password") != "secret" {
+				io.WriteString(w, "Fails.")
+				return
+			}
+			http.SetCookie(w, &http.Cookie{Name: "SID", Value: "session", Path: "/"})
+			io.WriteString(w, "Ok.")
+			return
+		}
+		if cookie, err := r.Cookie("SID"); err != nil || cookie.Value != "session" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/api/v2/torrents/add":
+			if err := r.ParseMultipartForm(1 << 20); err != nil {
+				t.Errorf("Can't parse multipart form: %v", err)
+			}
+			file, header, err := r.FormFile("torrents")
+			if err != nil {
+				*requests = append(*requests, "add urls="+r.FormValue("urls"))
+			} else {
+				data, _ := io.ReadAll(file)
+				*requests = append(*requests, "add "+header.Filename+" "+string(data)+" savepath="+r.FormValue("savepath")+
+					" category="+r.FormValue("category")+" tags="+r.FormValue("tags")+" paused="+r.FormValue("paused")+
+					" skip_checking="+r.FormValue("skip_checking")+" contentLayout="+r.FormValue("contentLayout"))
+			}
+			io.WriteString(w, "Ok.")
+		case "/api/v2/torrents/filePrio", "/api/v2/torrents/renameFile":
+			if !conflicts[r.URL.Path] {
+				conflicts[r.URL.Path] = true
+				w.WriteHeader(http.StatusConflict)
+				return
+			}
+			r.ParseForm()
+			*requests = append(*requests, r.URL.Path[len("/api/v2/torrents/"):]+" "+r.PostForm.Encode())
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestClient(t *testing.T) {
+	var requests []string
+	server := fakeServer(t, &requests)
+	defer server.Close()
+
+	client, err := NewClient(server.URL + "/")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	client.RetryDelay = 0
+	if err = client.Login("admin", "wrong"); err == nil {
+		t.Fatalf("Login with wrong password must fail, but it doesn't")
+	}
+	if err = client.Login("admin", "secret"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	params := &AddParams{Savepath: "/mnt/torrents/", Category: "films", Tags: []string{"tag1", "tag2"}, Paused: true, ContentLayout: "Original"}
+	if err = client.AddTorrent(params, "hash.torrent", []byte("torrent"), ""); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err = client.AddTorrent(params, "hash", nil, "magnet:?xt=urn:btih:hash"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err = client.SetFilePriority("hash", []int{0, 2}, 0); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err = client.RenameFile("hash", "dir/old.txt", "dir/new.txt"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := []string{
+		"add hash.torrent torrent savepath=/mnt/torrents/ category=films tags=tag1,tag2 paused=true skip_checking=false contentLayout=Original",
+		"add urls=magnet:?xt=urn:btih:hash",
+		"filePrio hash=hash&id=0%7C2&priority=0",
+		"renameFile hash=hash&newPath=dir%2Fnew.txt&oldPath=dir%2Fold.txt",
+	}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Fatalf("Unexpected requests:\nGot: %#v\nExpect: %#v", requests, expected)
+	}
+
+	if _, err = client.post("torrents/unknown", nil); err == nil {
+		t.Fatalf("Unknown method must fail, but it doesn't")
+	}
+}
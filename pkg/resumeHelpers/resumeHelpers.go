@@ -0,0 +1,117 @@
+package resumeHelpers
+
+/* Helpers for building uTorrent resume items from resume data of other clients */
+import (
+	"crypto/sha1"
+	"fmt"
+	"strings"
+
+	"github.com/rumanzo/bt2qbt/pkg/fileHelpers"
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
+	"github.com/zeebo/bencode"
+)
+
+// ReadTorrentFile decode torrent file to structure and to raw map with info as it is in file
+func ReadTorrentFile(path string) (*torrentStructures.Torrent, map[string]interface{}, error) {
+	torrentFile := &torrentStructures.Torrent{}
+	if err := helpers.DecodeTorrentFile(path, torrentFile); err != nil {
+		return nil, nil, err
+	}
+	torrentFileRaw, err := helpers.DecodeTorrentFileRaw(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return torrentFile, torrentFileRaw, nil
+}
+
+// InfoHash return binary v1 info hash like uTorrent store it in info field
+func InfoHash(torrentFile *torrentStructures.Torrent, torrentFileRaw map[string]interface{}) (string, error) {
+	if torrentFile.Info == nil {
+		return "", fmt.Errorf("torrent file doesn't contain info")
+	}
+	info, err := bencode.EncodeBytes(torrentFileRaw["info"])
+	if err != nil {
+		return "", err
+	}
+	hash := sha1.Sum(info)
+	return string(hash[:]), nil
+}
+
+// FileList return paths of torrent files, single file torrent contain only its name
+func FileList(torrentFile *torrentStructures.Torrent) []string {
+	if torrentFile.IsSingle() {
+		return []string{torrentFile.GetTorrentName()}
+	}
+	fileList, _ := torrentFile.GetFileList()
+	return fileList
+}
+
+// Priority return priorities in uTorrent layout. uTorrent store additional byte for every file of v2 and hybrid
+// torrents, transfer trims it
+func Priority(torrentFile *torrentStructures.Torrent, prio []byte) []byte {
+	if !torrentFile.IsV2OrHybryd() {
+		return prio
+	}
+	doubled := make([]byte, 0, len(prio)*2)
+	for _, p := range prio {
+		doubled = append(doubled, p, 128)
+	}
+	return doubled
+}
+
+// Targets return uTorrent targets for renamed files. Files contain torrent name as first part of path,
+// empty files aren't renamed
+func Targets(files []string, fileList []string) [][]interface{} {
+	var targets [][]interface{}
+	for index, file := range files {
+		if index >= len(fileList) {
+			break
+		}
+		if file == "" {
+			continue
+		}
+		file = fileHelpers.Normalize(file, `/`)
+		if parts := strings.SplitN(file, `/`, 2); len(parts) == 2 {
+			file = parts[1]
+		}
+		if file != fileList[index] {
+			targets = append(targets, []interface{}{int64(index), file})
+		}
+	}
+	return targets
+}
+
+// HaveAll return have bitfield of completed torrent
+func HaveAll(numPieces int64) []byte {
+	have := make([]byte, (numPieces+7)/8)
+	for i := int64(0); i < numPieces; i++ {
+		have[i/8] |= 0x80 >> uint(i%8)
+	}
+	return have
+}
+
+// HaveFromPieces convert libtorrent pieces, one byte per piece, to have bitfield. High bit of first byte is first piece
+func HaveFromPieces(pieces []byte) []byte {
+	if len(pieces) == 0 {
+		return nil
+	}
+	have := make([]byte, (len(pieces)+7)/8)
+	for i, piece := range pieces {
+		if piece&1 != 0 {
+			have[i/8] |= 0x80 >> uint(i%8)
+		}
+	}
+	return have
+}
+
+// Trackers return announce-list or announce of torrent file
+func Trackers(torrentFileRaw map[string]interface{}) interface{} {
+	if announceList, ok := torrentFileRaw["announce-list"]; ok {
+		return announceList
+	}
+	if announce, ok := torrentFileRaw["announce"]; ok {
+		return announce
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package resumeHelpers
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rumanzo/bt2qbt/pkg/torrentStructures"
+)
+
+func TestTargets(t *testing.T) {
+	type TargetsCase struct {
+		name     string
+		files    []string
+		fileList []string
+		expected [][]interface{}
+	}
+	cases := []TargetsCase{
+		{
+			name:     "001 not renamed files",
+			files:    []string{"dir/file1.txt", "dir/sub/file2.txt"},
+			fileList: []string{"file1.txt", "sub/file2.txt"},
+		},
+		{
+			name:     "002 renamed files with windows separators",
+			files:    []string{"dir\\renamed.txt", "", "dir/file3.txt"},
+			fileList: []string{"file1.txt", "file2.txt", "file3.txt"},
+			expected: [][]interface{}{{int64(0), "renamed.txt"}},
+		},
+		{
+			name:     "003 more files than in torrent",
+			files:    []string{"dir/file1.txt", "dir/extra.txt"},
+			fileList: []string{"file1.txt"},
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			targets := Targets(testCase.files, testCase.fileList)
+			if !reflect.DeepEqual(targets, testCase.expected) {
+				t.Fatalf("Unexpected error: opts isn't equal:\n Got: %#v\n Expect %#v\n", targets, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestPriority(t *testing.T) {
+	v1 := &torrentStructures.Torrent{Info: &torrentStructures.TorrentInfo{Pieces: []byte("01234567890123456789")}}
+	if prio := Priority(v1, []byte{8, 128}); !reflect.DeepEqual(prio, []byte{8, 128}) {
+		t.Fatalf("Unexpected v1 priorities %v", prio)
+	}
+	v2 := &torrentStructures.Torrent{Info: &torrentStructures.TorrentInfo{MetaVersion: 2, FileTree: map[string]interface{}{}}}
+	if prio := Priority(v2, []byte{8, 15}); !reflect.DeepEqual(prio, []byte{8, 128, 15, 128}) {
+		t.Fatalf("Unexpected v2 priorities %v", prio)
+	}
+}
+
+func TestHave(t *testing.T) {
+	if have := HaveAll(10); !reflect.DeepEqual(have, []byte{0xff, 0xc0}) {
+		t.Fatalf("Unexpected have of completed torrent %x", have)
+	}
+	if have := HaveFromPieces([]byte{1, 0, 1, 1, 0, 0, 0, 0, 3}); !reflect.DeepEqual(have, []byte{0xb0, 0x80}) {
+		t.Fatalf("Unexpected have %x", have)
+	}
+	if have := HaveFromPieces(nil); have != nil {
+		t.Fatalf("Unexpected have of torrent without pieces %x", have)
+	}
+}
@@ -0,0 +1,29 @@
+package rtorrentStructures
+
+// https://github.com/rakshasa/rtorrent/blob/master/src/core/download_store.cc
+
+// RTorrentSession content of <HASH>.torrent.rtorrent file
+type RTorrentSession struct {
+	Complete          int64             `bencode:"complete"`
+	Custom            map[string]string `bencode:"custom,omitempty"`  // custom fields, ruTorrent store addtime here
+	Custom1           string            `bencode:"custom1,omitempty"` // ruTorrent label, url encoded
+	Directory         string            `bencode:"directory"`         // directory of multi file torrent or directory contains single file
+	DirectoryBase     string            `bencode:"directory_base"`
+	State             int64             `bencode:"state"` // 1 if torrent started, 0 if stopped
+	TimestampFinished int64             `bencode:"timestamp.finished"`
+	TimestampStarted  int64             `bencode:"timestamp.started"`
+	TotalDownloaded   int64             `bencode:"total_downloaded"`
+	TotalUploaded     int64             `bencode:"total_uploaded"`
+}
+
+// LibtorrentResume content of <HASH>.torrent.libtorrent_resume file
+type LibtorrentResume struct {
+	Bitfield interface{}            `bencode:"bitfield"` // bitfield of pieces (high bit of first byte is first piece) or number of pieces if all done
+	Files    []LibtorrentResumeFile `bencode:"files"`
+}
+
+type LibtorrentResumeFile struct {
+	Completed int64 `bencode:"completed"`
+	Mtime     int64 `bencode:"mtime"`
+	Priority  int64 `bencode:"priority"` // 0 off, 1 normal, 2 high
+}
@@ -0,0 +1,176 @@
+package torrentIndex
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/rumanzo/bt2qbt/pkg/helpers"
+)
+
+// Entry location of torrent file. Path is path inside archive if Archive isn't empty
+type Entry struct {
+	Path    string
+	Archive string
+}
+
+// String return path of torrent file, or archive!entry for torrent files inside archive
+func (entry Entry) String() string {
+	if entry.Archive == "" {
+		return entry.Path
+	}
+	return entry.Archive + "!" + entry.Path
+}
+
+// Index of torrent files by lowercase hex SHA-1 info hash
+type Index struct {
+	entries map[string]Entry
+	tempDir string
+	mu      sync.Mutex
+}
+
+// Build walk search paths recursively and index all torrent files. Torrent files inside zip archives are indexed if
+// withZip is set. Search paths that don't exist, files that can't be read or parsed are skipped
+func Build(searchPaths []string, withZip bool) (*Index, error) {
+	index := &Index{entries: map[string]Entry{}}
+	for _, searchPath := range searchPaths {
+		if _, err := os.Stat(searchPath); errors.Is(err, os.ErrNotExist) {
+			log.Printf("Search path %v doesn't exist, it isn't indexed\n", searchPath)
+			continue
+		}
+		err := filepath.WalkDir(searchPath, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				// unreadable directories are skipped, but unreadable search path is error
+				if path == searchPath {
+					return err
+				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
+			switch strings.ToLower(filepath.Ext(path)) {
+			case ".torrent":
+				if data, err := os.ReadFile(path); err == nil {
+					index.add(data, Entry{Path: path})
+				}
+			case ".zip":
+				if withZip {
+					index.addZip(path)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("can't index search path %v: %v", searchPath, err)
+		}
+	}
+	return index, nil
+}
+
+func (index *Index) add(data []byte, entry Entry) {
+	info, err := helpers.GetRawInfo(data)
+	if err != nil {
+		return
+	}
+	hash := sha1.Sum(info)
+	key := hex.EncodeToString(hash[:])
+	// first found file wins, search paths are in order of priority
+	if _, ok := index.entries[key]; !ok {
+		index.entries[key] = entry
+	}
+}
+
+func (index *Index) addZip(archive string) {
+	reader, err := zip.OpenReader(archive)
+	if err != nil {
+		return
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		if file.FileInfo().IsDir() || strings.ToLower(filepath.Ext(file.Name)) != ".torrent" {
+			continue
+		}
+		if data, err := readZipFile(file); err == nil {
+			index.add(data, Entry{Path: file.Name, Archive: archive})
+		}
+	}
+}
+
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+func (index *Index) Len() int {
+	return len(index.entries)
+}
+
+// Lookup return location of torrent file with info hash
+func (index *Index) Lookup(hash string) (Entry, bool) {
+	entry, ok := index.entries[strings.ToLower(hash)]
+	return entry, ok
+}
+
+// Find return path of torrent file with info hash. Torrent files from archives are extracted to temporary directory.
+// Empty path is returned if index doesn't contain torrent
+func (index *Index) Find(hash string) (string, error) {
+	entry, ok := index.Lookup(hash)
+	if !ok {
+		return "", nil
+	}
+	if entry.Archive == "" {
+		return entry.Path, nil
+	}
+
+	index.mu.Lock()
+	defer index.mu.Unlock()
+	if index.tempDir == "" {
+		tempDir, err := os.MkdirTemp("", "bt2qbt")
+		if err != nil {
+			return "", err
+		}
+		index.tempDir = tempDir
+	}
+	path := filepath.Join(index.tempDir, strings.ToLower(hash)+".torrent")
+	if _, err := os.Stat(path); err == nil {
+		return path, nil
+	}
+	reader, err := zip.OpenReader(entry.Archive)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	for _, file := range reader.File {
+		if file.Name != entry.Path {
+			continue
+		}
+		data, err := readZipFile(file)
+		if err != nil {
+			return "", fmt.Errorf("can't extract %v from %v: %v", entry.Path, entry.Archive, err)
+		}
+		return path, os.WriteFile(path, data, 0644)
+	}
+	return "", fmt.Errorf("can't find %v in %v", entry.Path, entry.Archive)
+}
+
+// Close remove torrent files extracted from archives
+func (index *Index) Close() error {
+	if index.tempDir == "" {
+		return nil
+	}
+	return os.RemoveAll(index.tempDir)
+}
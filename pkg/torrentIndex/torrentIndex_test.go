@@ -0,0 +1,103 @@
+package torrentIndex
+
+import (
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIndex(t *testing.T) {
+	v1, err := os.ReadFile("../../test/data/testdir_v1.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hybrid, err := os.ReadFile("../../test/data/testdir_hybrid.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// renamed torrent file in nested directory, torrent file in zip archive and broken torrent file
+	dir := t.TempDir()
+	if err = os.MkdirAll(filepath.Join(dir, "nested", "deeper"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "nested", "deeper", "renamed.torrent"), v1, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, "broken.torrent"), []byte("d4:info"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	archive, err := os.Create(filepath.Join(dir, "nested", "archive.zip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writer := zip.NewWriter(archive)
+	file, err := writer.Create("torrents/hybrid.torrent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write(hybrid)
+	writer.Close()
+	archive.Close()
+
+	type IndexCase struct {
+		name     string
+		withZip  bool
+		hash     string
+		expected []byte
+	}
+	cases := []IndexCase{
+		{
+			name:     "001 renamed torrent file",
+			hash:     "3456BAC107634970B022677C6BFAA584065E0917",
+			expected: v1,
+		},
+		{
+			name: "002 torrent file in archive without zip indexing",
+			hash: "909256a0b8baac2b01ee6089ba21f9eabf3a0995",
+		},
+		{
+			name:     "003 torrent file in archive",
+			withZip:  true,
+			hash:     "909256a0b8baac2b01ee6089ba21f9eabf3a0995",
+			expected: hybrid,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			index, err := Build([]string{dir}, testCase.withZip)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			defer index.Close()
+			path, err := index.Find(testCase.hash)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if testCase.expected == nil {
+				if path != "" {
+					t.Fatalf("Unexpected path %v", path)
+				}
+				return
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !bytes.Equal(data, testCase.expected) {
+				t.Fatalf("Unexpected torrent file %v", path)
+			}
+		})
+	}
+
+	// default search paths may not exist
+	index, err := Build([]string{filepath.Join(dir, "notexists"), dir}, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer index.Close()
+	if index.Len() != 1 {
+		t.Fatalf("Unexpected number of indexed torrent files %v", index.Len())
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"github.com/rumanzo/bt2qbt/pkg/helpers"
 	"github.com/rumanzo/bt2qbt/pkg/normalization"
 	"sort"
+	"strings"
 )
 
 func (t *Torrent) IsV2OrHybryd() bool {
@@ -14,6 +15,11 @@ func (t *Torrent) IsV2OrHybryd() bool {
 	return false
 }
 
+// IsV2 pure v2 torrents have file tree without v1 pieces
+func (t *Torrent) IsV2() bool {
+	return t.IsV2OrHybryd() && len(t.Info.Pieces) == 0
+}
+
 func (t *Torrent) IsSingle() bool {
 	if t.Single != nil {
 		return *t.Single
@@ -125,6 +131,49 @@ func getFileListV2(f interface{}) ([]FilepathLength, bool) {
 	return nfiles, normalized
 }
 
+// IsPadding return true if file is padding file and doesn't exist on disk
+func (f *TorrentFile) IsPadding() bool {
+	return strings.Contains(f.Attr, "p")
+}
+
+// GetPiecesRoots return pieces roots of v2 file tree in the same order as GetFileListWB
+func (t *Torrent) GetPiecesRoots() [][]byte {
+	if !t.IsV2OrHybryd() {
+		return nil
+	}
+	return getPiecesRootsV2(t.Info.FileTree)
+}
+
+func getPiecesRootsV2(f interface{}) [][]byte {
+	var roots [][]byte
+	tree, ok := f.(map[string]interface{})
+	if !ok {
+		return roots
+	}
+	keys := make([]string, 0, len(tree))
+	for k := range tree {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if len(k) == 0 { // it's means that next will be structure with length and piece root
+			var root []byte
+			if entry, ok := tree[k].(map[string]interface{}); ok {
+				switch r := entry["pieces root"].(type) {
+				case string:
+					root = []byte(r)
+				case []byte:
+					root = r
+				}
+			}
+			return append(roots, root)
+		}
+		roots = append(roots, getPiecesRootsV2(tree[k])...)
+	}
+	return roots
+}
+
 func (t *Torrent) GetTorrentName() string {
 	if t.Info.NameUTF8 != "" {
 		return t.Info.NameUTF8
@@ -4,14 +4,16 @@ type Torrent struct {
 	Announce       string                  `bencode:"announce"`
 	Comment        string                  `bencode:"comment"`
 	CreatedBy      string                  `bencode:"created by"`
-	CreationDate   interface{}             `bencode:"creation date"` // can't be string or int64
+	CreationDate   interface{}             `bencode:"creation date"`       // can't be string or int64
+	HttpSeeds      interface{}             `bencode:"httpseeds,omitempty"` // BEP 17, string or list of strings
 	Info           *TorrentInfo            `bencode:"info"`
 	Publisher      string                  `bencode:"publisher,omitempty"`
 	PublisherUrl   string                  `bencode:"publisher-url,omitempty"`
 	PieceLayers    *map[string]interface{} `bencode:"piece layers"`
-	FilePathLength *[]FilepathLength       `bencode:"-"` // service field
-	FilePaths      *[]string               `bencode:"-"` // service field
-	Single         *bool                   `bencode:"-"` // service field
+	UrlList        interface{}             `bencode:"url-list,omitempty"` // BEP 19, string or list of strings
+	FilePathLength *[]FilepathLength       `bencode:"-"`                  // service field
+	FilePaths      *[]string               `bencode:"-"`                  // service field
+	Single         *bool                   `bencode:"-"`                  // service field
 }
 
 type TorrentInfo struct {
@@ -31,6 +33,7 @@ type TorrentInfo struct {
 }
 
 type TorrentFile struct {
+	Attr     string   `bencode:"attr,omitempty"` // p for padding files http://bittorrent.org/beps/bep_0047.html
 	Length   int64    `bencode:"length,omitempty"`
 	Md5sum   string   `bencode:"md5sum,omitempty"`
 	Path     []string `bencode:"path,omitempty"`
@@ -0,0 +1,9 @@
+//go:build !(windows && 386)
+
+package torrentsDb
+
+import (
+	_ "modernc.org/sqlite"
+)
+
+const driverName = "sqlite"
@@ -0,0 +1,6 @@
+//go:build windows && 386
+
+package torrentsDb
+
+// modernc.org/sqlite can't be built for 32-bit windows
+const driverName = ""
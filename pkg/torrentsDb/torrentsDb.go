@@ -0,0 +1,140 @@
+package torrentsDb
+
+/*
+qBittorrent 4.4+ can store resume data in sqlite database torrents.db instead of fastresume files
+https://github.com/qbittorrent/qBittorrent/blob/master/src/base/bittorrent/dbresumedatastorage.cpp
+*/
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+var ErrUnsupported = errors.New("SQLite output isn't supported on this platform")
+
+// Supported schema versions. Version 1 is schema of qBittorrent 4.4.0, later versions only add columns with default
+// values (download_path, stop_condition, inactive_seeding_time_limit), so rows with columns of version 1 fit all of them
+const (
+	MinSchemaVersion = 1
+	MaxSchemaVersion = 5
+)
+
+// SchemaVersion version of schema that will be created in new database. qBittorrent upgrades it to own version on start
+const SchemaVersion = MinSchemaVersion
+
+// Row of torrents table
+type Row struct {
+	TorrentId              string
+	QueuePosition          int64
+	Name                   string
+	Category               string
+	Tags                   string
+	TargetSavePath         string
+	ContentLayout          string
+	RatioLimit             int64
+	SeedingTimeLimit       int64
+	HasOuterPiecesPriority int64
+	HasSeedStatus          int64
+	OperatingMode          string // AutoManaged or Forced
+	Stopped                int64
+	LibtorrentResumeData   []byte
+	Metadata               []byte
+}
+
+type TorrentsDb struct {
+	db      *sql.DB
+	version int64
+	mu      sync.Mutex
+}
+
+// Open open torrents.db and read its schema version. Database with current schema will be created if it doesn't exist
+func Open(path string) (*TorrentsDb, error) {
+	if driverName == "" {
+		return nil, ErrUnsupported
+	}
+	db, err := sql.Open(driverName, path)
+	if err != nil {
+		return nil, err
+	}
+	torrentsDb := &TorrentsDb{db: db}
+	if torrentsDb.version, err = readVersion(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return torrentsDb, nil
+}
+
+func readVersion(db *sql.DB) (int64, error) {
+	var tables int64
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('meta', 'torrents')`).Scan(&tables); err != nil {
+		return 0, err
+	}
+	if tables == 0 {
+		return SchemaVersion, createSchema(db)
+	} else if tables != 2 {
+		return 0, errors.New("torrents.db doesn't contain meta or torrents table")
+	}
+
+	var value interface{}
+	if err := db.QueryRow(`SELECT value FROM meta WHERE name = 'version'`).Scan(&value); err != nil {
+		return 0, fmt.Errorf("can't read torrents.db schema version: %v", err)
+	}
+	var version int64
+	switch v := value.(type) {
+	case int64:
+		version = v
+	case []byte:
+		version, _ = strconv.ParseInt(string(v), 10, 64)
+	case string:
+		version, _ = strconv.ParseInt(v, 10, 64)
+	}
+	if version < MinSchemaVersion || version > MaxSchemaVersion {
+		return 0, fmt.Errorf("unsupported torrents.db schema version %v, supported versions are %v-%v",
+			value, MinSchemaVersion, MaxSchemaVersion)
+	}
+	return version, nil
+}
+
+func createSchema(db *sql.DB) error {
+	statements := []string{
+		`CREATE TABLE meta (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE, value BLOB)`,
+		`CREATE TABLE torrents (id INTEGER PRIMARY KEY, torrent_id BLOB NOT NULL UNIQUE, queue_position INTEGER NOT NULL DEFAULT -1, ` +
+			`name TEXT, category TEXT, tags TEXT, target_save_path TEXT, contentLayout TEXT NOT NULL, ` +
+			`ratio_limit INTEGER NOT NULL, seeding_time_limit INTEGER NOT NULL, ` +
+			`has_outer_pieces_priority INTEGER NOT NULL, has_seed_status INTEGER NOT NULL, operating_mode TEXT NOT NULL, ` +
+			`stopped INTEGER NOT NULL, libtorrent_resume_data BLOB NOT NULL, metadata BLOB)`,
+		`CREATE INDEX torrent_id_index ON torrents (torrent_id)`,
+		fmt.Sprintf(`INSERT INTO meta (name, value) VALUES ('version', %v)`, SchemaVersion),
+	}
+	for _, statement := range statements {
+		if _, err := db.Exec(statement); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *TorrentsDb) Version() int64 {
+	return t.version
+}
+
+// Insert add torrent or replace it if torrent with same id already exists. Only columns of schema version 1 are written,
+// columns of later versions get their default values
+func (t *TorrentsDb) Insert(row *Row) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err := t.db.Exec(`INSERT OR REPLACE INTO torrents (torrent_id, queue_position, name, category, tags, target_save_path, `+
+		`contentLayout, ratio_limit, seeding_time_limit, has_outer_pieces_priority, has_seed_status, operating_mode, stopped, `+
+		`libtorrent_resume_data, metadata) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		row.TorrentId, row.QueuePosition, row.Name, row.Category, row.Tags, row.TargetSavePath, row.ContentLayout,
+		row.RatioLimit, row.SeedingTimeLimit, row.HasOuterPiecesPriority, row.HasSeedStatus, row.OperatingMode, row.Stopped,
+		row.LibtorrentResumeData, row.Metadata)
+	return err
+}
+
+func (t *TorrentsDb) Close() error {
+	return t.db.Close()
+}
@@ -0,0 +1,155 @@
+package torrentsDb
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func TestInsert(t *testing.T) {
+	if driverName == "" {
+		t.Skip(ErrUnsupported)
+	}
+	type InsertCase struct {
+		name            string
+		schema          []string
+		expectedVersion int64
+		mustFail        bool
+	}
+	cases := []InsertCase{
+		{
+			name:            "001 new database",
+			expectedVersion: SchemaVersion,
+		},
+		{
+			name: "002 database with schema version 1",
+			schema: []string{
+				`CREATE TABLE meta (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE, value BLOB)`,
+				`INSERT INTO meta (name, value) VALUES ('version', '1')`,
+				`CREATE TABLE torrents (id INTEGER PRIMARY KEY, torrent_id BLOB NOT NULL UNIQUE, queue_position INTEGER NOT NULL DEFAULT -1, ` +
+					`name TEXT, category TEXT, tags TEXT, target_save_path TEXT, contentLayout TEXT NOT NULL, ratio_limit INTEGER NOT NULL, ` +
+					`seeding_time_limit INTEGER NOT NULL, has_outer_pieces_priority INTEGER NOT NULL, has_seed_status INTEGER NOT NULL, ` +
+					`operating_mode TEXT NOT NULL, stopped INTEGER NOT NULL, libtorrent_resume_data BLOB NOT NULL, metadata BLOB NOT NULL)`,
+			},
+			expectedVersion: 1,
+		},
+		{
+			name: "003 database with schema version 5",
+			schema: []string{
+				`CREATE TABLE meta (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE, value BLOB)`,
+				`INSERT INTO meta (name, value) VALUES ('version', 5)`,
+				`CREATE TABLE torrents (id INTEGER PRIMARY KEY, torrent_id BLOB NOT NULL UNIQUE, queue_position INTEGER NOT NULL DEFAULT -1, ` +
+					`name TEXT, category TEXT, tags TEXT, target_save_path TEXT, download_path TEXT, contentLayout TEXT NOT NULL, ` +
+					`ratio_limit INTEGER NOT NULL, seeding_time_limit INTEGER NOT NULL, inactive_seeding_time_limit INTEGER NOT NULL DEFAULT -2, ` +
+					`has_outer_pieces_priority INTEGER NOT NULL, has_seed_status INTEGER NOT NULL, operating_mode TEXT NOT NULL, ` +
+					`stopped INTEGER NOT NULL, stop_condition TEXT NOT NULL DEFAULT 'None', libtorrent_resume_data BLOB NOT NULL, metadata BLOB)`,
+			},
+			expectedVersion: 5,
+		},
+		{
+			name: "004 database with unsupported schema version. mustFail",
+			schema: []string{
+				`CREATE TABLE meta (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE, value BLOB)`,
+				`INSERT INTO meta (name, value) VALUES ('version', 6)`,
+				`CREATE TABLE torrents (id INTEGER PRIMARY KEY, torrent_id BLOB NOT NULL UNIQUE)`,
+			},
+			mustFail: true,
+		},
+		{
+			name: "005 database without torrents table. mustFail",
+			schema: []string{
+				`CREATE TABLE meta (id INTEGER PRIMARY KEY, name TEXT NOT NULL UNIQUE, value BLOB)`,
+			},
+			mustFail: true,
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "torrents.db")
+			if testCase.schema != nil {
+				db, err := sql.Open(driverName, path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, statement := range testCase.schema {
+					if _, err = db.Exec(statement); err != nil {
+						t.Fatal(err)
+					}
+				}
+				db.Close()
+			}
+
+			torrentsDb, err := Open(path)
+			if err != nil && !testCase.mustFail {
+				t.Fatalf("Unexpected error: %v", err)
+			} else if err == nil && testCase.mustFail {
+				t.Fatalf("Test must fail, but it doesn't")
+			}
+			if testCase.mustFail {
+				return
+			}
+			defer torrentsDb.Close()
+			if torrentsDb.Version() != testCase.expectedVersion {
+				t.Fatalf("Unexpected schema version. Got %v, expect %v", torrentsDb.Version(), testCase.expectedVersion)
+			}
+
+			row := &Row{
+				TorrentId:            "0123456789abcdef0123456789abcdef01234567",
+				QueuePosition:        3,
+				Category:             "films",
+				Tags:                 "tag1,tag2",
+				ContentLayout:        "Original",
+				OperatingMode:        "AutoManaged",
+				Stopped:              1,
+				LibtorrentResumeData: []byte("de"),
+				Metadata:             []byte("de"),
+			}
+			// second insert of the same torrent must replace first
+			for i := 0; i < 2; i++ {
+				if err = torrentsDb.Insert(row); err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+			}
+			var count, queuePosition int64
+			var category, operatingMode string
+			if err = torrentsDb.db.QueryRow(`SELECT COUNT(*), queue_position, category, operating_mode FROM torrents`).Scan(
+				&count, &queuePosition, &category, &operatingMode); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if count != 1 || queuePosition != row.QueuePosition || category != row.Category || operatingMode != row.OperatingMode {
+				t.Fatalf("Unexpected row. Got %v rows with queue position %v, category %v and operating mode %v",
+					count, queuePosition, category, operatingMode)
+			}
+		})
+	}
+}
+
+func TestInsertMagnet(t *testing.T) {
+	if driverName == "" {
+		t.Skip(ErrUnsupported)
+	}
+	torrentsDb, err := Open(filepath.Join(t.TempDir(), "torrents.db"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer torrentsDb.Close()
+
+	// magnet links haven't metadata until qBittorrent download it
+	row := &Row{
+		TorrentId:            "0123456789abcdef0123456789abcdef01234567",
+		QueuePosition:        -1,
+		ContentLayout:        "Original",
+		OperatingMode:        "AutoManaged",
+		LibtorrentResumeData: []byte("de"),
+	}
+	if err = torrentsDb.Insert(row); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var metadata []byte
+	if err = torrentsDb.db.QueryRow(`SELECT metadata FROM torrents`).Scan(&metadata); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if metadata != nil {
+		t.Fatalf("Unexpected metadata %v", metadata)
+	}
+}
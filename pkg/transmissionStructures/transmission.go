@@ -0,0 +1,27 @@
+package transmissionStructures
+
+// https://github.com/transmission/transmission/blob/main/libtransmission/resume.cc
+
+type TransmissionResume struct {
+	ActivityDate int64    `bencode:"activity-date"`
+	AddedDate    int64    `bencode:"added-date"`
+	Destination  string   `bencode:"destination"`
+	Dnd          []int64  `bencode:"dnd"` // one entry per file, 1 if file isn't wanted
+	DoneDate     int64    `bencode:"done-date"`
+	Downloaded   int64    `bencode:"downloaded"`
+	Files        []string `bencode:"files,omitempty"`  // renamed files, full paths including torrent name
+	Labels       []string `bencode:"labels,omitempty"` // since transmission 3.00
+	Name         string   `bencode:"name,omitempty"`   // renamed torrent name
+	Paused       int64    `bencode:"paused"`
+	Priority     []int64  `bencode:"priority"` // one entry per file, -1 low, 0 normal, 1 high
+	Progress     Progress `bencode:"progress"`
+	Uploaded     int64    `bencode:"uploaded"`
+}
+
+// Progress contain downloaded blocks. Legacy have "all" for completed torrent, otherwise bitfield of pieces.
+// High bit of first byte of bitfields is first block or piece
+type Progress struct {
+	Bitfield []byte      `bencode:"bitfield,omitempty"`
+	Blocks   interface{} `bencode:"blocks,omitempty"` // "all", "none" or bitfield of 16 KiB blocks
+	Have     string      `bencode:"have,omitempty"`
+}
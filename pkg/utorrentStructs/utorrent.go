@@ -4,18 +4,34 @@ type ResumeItem struct {
 	AddedOn          int64           `bencode:"added_on"`
 	Caption          string          `bencode:"caption,omitempty"`
 	CompletedOn      int64           `bencode:"completed_on"`
+	Dht              *int64          `bencode:"dht,omitempty"` // 0 if DHT is disabled for torrent
+	DownSpeed        int64           `bencode:"downspeed"`
 	Downloaded       int64           `bencode:"downloaded"`
+	Have             []byte          `bencode:"have,omitempty"`      // bitfield of downloaded pieces, high bit of first byte is first piece
+	HttpSeeds        interface{}     `bencode:"httpseeds,omitempty"` // added by user
 	Info             string          `bencode:"info"`
 	Label            string          `bencode:"label,omitempty"`
 	Labels           []string        `bencode:"labels,omitempty"`
+	LastActive       int64           `bencode:"last_active"` // time of last upload or download
 	LastSeenComplete int64           `bencode:"last_seen_complete"`
+	OverrideSeed     int64           `bencode:"override_seedsettings"` // 1 if torrent has own seeding goals
 	Path             string          `bencode:"path"`
+	Peers            string          `bencode:"peers,omitempty"`  // compact IPv4 peers, 4 bytes address and 2 bytes port
+	Peers6           string          `bencode:"peers6,omitempty"` // compact IPv6 peers, 16 bytes address and 2 bytes port. IPv4 peers are mapped
+	Pex              *int64          `bencode:"pex,omitempty"`    // 0 if peer exchange is disabled for torrent
 	Prio             []byte          `bencode:"prio"`
 	Runtime          int64           `bencode:"runtime"`
+	SeedTime         int64           `bencode:"seedtime"` // seconds
+	Sequential       int64           `bencode:"sequential,omitempty"`
 	Started          int64           `bencode:"started"`
+	SuperSeed        int64           `bencode:"superseed"`
 	Targets          [][]interface{} `bencode:"targets,omitempty"`
 	Time             int64           `bencode:"time"`
 	Trackers         interface{}     `bencode:"trackers,omitempty"`
+	UlSlots          int64           `bencode:"ulslots"`            // 0 if global upload slots are used
+	UrlList          interface{}     `bencode:"url-list,omitempty"` // web seeds added by user
 	UpSpeed          int64           `bencode:"upspeed"`
 	Uploaded         int64           `bencode:"uploaded"`
+	WantedRatio      int64           `bencode:"wanted_ratio"`    // per mille
+	WantedSeedtime   int64           `bencode:"wanted_seedtime"` // seconds
 }
@@ -0,0 +1,256 @@
+package verification
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"io"
+	"os"
+	"sync"
+)
+
+// BlockSize size of merkle tree leaf for v2 torrents. http://bittorrent.org/beps/bep_0052.html
+const BlockSize = 16 * 1024
+
+// File one file of torrent on disk. Padding files isn't exists on disk and always contain zeros
+type File struct {
+	Path       string
+	Length     int64
+	Padding    bool
+	PiecesRoot []byte // only for v2 torrents
+}
+
+type job struct {
+	data     []byte
+	expected []byte
+	check    func(data []byte, expected []byte) bool
+	result   *bool
+	wg       *sync.WaitGroup
+}
+
+// Hasher bounded pool of workers that hash pieces. One hasher can be shared between several torrents
+type Hasher struct {
+	jobs chan *job
+}
+
+func NewHasher(workers int) *Hasher {
+	if workers < 1 {
+		workers = 1
+	}
+	hasher := &Hasher{jobs: make(chan *job, workers)}
+	for i := 0; i < workers; i++ {
+		go hasher.work()
+	}
+	return hasher
+}
+
+func (hasher *Hasher) work() {
+	for j := range hasher.jobs {
+		*j.result = j.check(j.data, j.expected)
+		j.wg.Done()
+	}
+}
+
+// Close stop workers. Hasher can't be used after it
+func (hasher *Hasher) Close() {
+	close(hasher.jobs)
+}
+
+func (hasher *Hasher) submit(wg *sync.WaitGroup, result *bool, data []byte, expected []byte, check func([]byte, []byte) bool) {
+	wg.Add(1)
+	hasher.jobs <- &job{data: data, expected: expected, check: check, result: result, wg: wg}
+}
+
+// VerifyV1 check every piece of files sequence with SHA-1 hashes from torrent info pieces field
+func (hasher *Hasher) VerifyV1(files []File, pieceLength int64, pieces []byte) []bool {
+	numPieces := len(pieces) / sha1.Size
+	results := make([]bool, numPieces)
+	if pieceLength <= 0 {
+		return results
+	}
+	var wg sync.WaitGroup
+	reader := newSequenceReader(files)
+	defer reader.close()
+	for i := 0; i < numPieces; i++ {
+		data, ok := reader.read(pieceLength)
+		if !ok || len(data) == 0 {
+			continue
+		}
+		hasher.submit(&wg, &results[i], data, pieces[i*sha1.Size:(i+1)*sha1.Size], checkSHA1)
+	}
+	wg.Wait()
+	return results
+}
+
+// VerifyV2 check every piece of every file with merkle trees from torrent file tree and piece layers.
+// In v2 torrents every file starts with new piece, so pieces are counted for each file separately
+func (hasher *Hasher) VerifyV2(files []File, pieceLength int64, pieceLayers map[string]interface{}) []bool {
+	var results []bool
+	if pieceLength < BlockSize {
+		return results
+	}
+	var wg sync.WaitGroup
+	for _, file := range files {
+		if file.Padding || file.Length == 0 {
+			continue
+		}
+		numPieces := (file.Length + pieceLength - 1) / pieceLength
+		fileResults := make([]bool, numPieces)
+		results = append(results, fileResults...)
+		fileResults = results[len(results)-int(numPieces):]
+
+		var layer []byte
+		if numPieces > 1 {
+			if raw, ok := pieceLayers[string(file.PiecesRoot)]; ok {
+				switch l := raw.(type) {
+				case string:
+					layer = []byte(l)
+				case []byte:
+					layer = l
+				}
+			}
+			if int64(len(layer)) != numPieces*sha256.Size {
+				continue
+			}
+		}
+
+		reader := newSequenceReader([]File{file})
+		for i := int64(0); i < numPieces; i++ {
+			data, ok := reader.read(pieceLength)
+			if !ok {
+				break
+			}
+			if numPieces == 1 {
+				// file that fit in one piece hash directly with pieces root
+				hasher.submit(&wg, &fileResults[i], data, file.PiecesRoot, checkMerkleRoot)
+			} else {
+				width := int(pieceLength / BlockSize)
+				expected := layer[i*sha256.Size : (i+1)*sha256.Size]
+				hasher.submit(&wg, &fileResults[i], data, expected, func(data []byte, expected []byte) bool {
+					return bytes.Equal(MerkleRoot(data, width), expected)
+				})
+			}
+		}
+		reader.close()
+	}
+	wg.Wait()
+	return results
+}
+
+func checkSHA1(data []byte, expected []byte) bool {
+	sum := sha1.Sum(data)
+	return bytes.Equal(sum[:], expected)
+}
+
+func checkMerkleRoot(data []byte, expected []byte) bool {
+	return bytes.Equal(MerkleRoot(data, 0), expected)
+}
+
+// MerkleRoot calculate root of SHA-256 merkle tree with 16KiB leafs.
+// Tree padded with zero hashes to width leafs, or to next power of two if width is zero
+func MerkleRoot(data []byte, width int) []byte {
+	var layer [][]byte
+	for offset := 0; offset < len(data); offset += BlockSize {
+		end := offset + BlockSize
+		if end > len(data) {
+			end = len(data)
+		}
+		sum := sha256.Sum256(data[offset:end])
+		layer = append(layer, sum[:])
+	}
+	if width == 0 {
+		width = 1
+		for width < len(layer) {
+			width *= 2
+		}
+	}
+	zero := make([]byte, sha256.Size)
+	for len(layer) < width {
+		layer = append(layer, zero)
+	}
+	for len(layer) > 1 {
+		next := make([][]byte, 0, len(layer)/2)
+		for i := 0; i < len(layer); i += 2 {
+			h := sha256.New()
+			h.Write(layer[i])
+			h.Write(layer[i+1])
+			next = append(next, h.Sum(nil))
+		}
+		layer = next
+	}
+	if len(layer) == 0 {
+		return zero
+	}
+	return layer[0]
+}
+
+// sequenceReader read files one by one as one stream like pieces are laid out in v1 torrents
+type sequenceReader struct {
+	files   []File
+	index   int
+	current *os.File
+	left    int64
+	failed  bool
+}
+
+func newSequenceReader(files []File) *sequenceReader {
+	return &sequenceReader{files: files, index: -1}
+}
+
+func (reader *sequenceReader) close() {
+	if reader.current != nil {
+		reader.current.Close()
+		reader.current = nil
+	}
+}
+
+func (reader *sequenceReader) next() bool {
+	reader.close()
+	reader.index++
+	if reader.index >= len(reader.files) {
+		return false
+	}
+	file := reader.files[reader.index]
+	reader.left = file.Length
+	reader.failed = false
+	if !file.Padding && file.Length > 0 {
+		f, err := os.Open(file.Path)
+		if err != nil {
+			reader.failed = true
+		} else {
+			reader.current = f
+		}
+	}
+	return true
+}
+
+// read return next chunk with length up to size. ok is false if chunk contain data of missing or short file
+func (reader *sequenceReader) read(size int64) ([]byte, bool) {
+	data := make([]byte, 0, size)
+	ok := true
+	for int64(len(data)) < size {
+		if reader.left == 0 {
+			if !reader.next() {
+				break
+			}
+			continue
+		}
+		chunk := size - int64(len(data))
+		if chunk > reader.left {
+			chunk = reader.left
+		}
+		buf := make([]byte, chunk)
+		file := reader.files[reader.index]
+		if !file.Padding {
+			if reader.failed {
+				ok = false
+			} else if _, err := io.ReadFull(reader.current, buf); err != nil {
+				reader.failed = true
+				ok = false
+			}
+		}
+		data = append(data, buf...)
+		reader.left -= chunk
+	}
+	return data, ok
+}
@@ -0,0 +1,105 @@
+package verification
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMerkleRoot(t *testing.T) {
+	type Case struct {
+		name     string
+		data     []byte
+		width    int
+		expected []byte
+	}
+	block := bytes.Repeat([]byte{1}, BlockSize)
+	leaf := sha256.Sum256(block)
+	tail := sha256.Sum256([]byte{2})
+	zero := make([]byte, sha256.Size)
+	pair := func(a, b []byte) []byte {
+		h := sha256.New()
+		h.Write(a)
+		h.Write(b)
+		return h.Sum(nil)
+	}
+	cases := []Case{
+		{
+			name:     "001 one block",
+			data:     []byte{2},
+			expected: tail[:],
+		},
+		{
+			name:     "002 two blocks",
+			data:     append(append([]byte{}, block...), 2),
+			expected: pair(leaf[:], tail[:]),
+		},
+		{
+			name:     "003 three blocks padded to power of two",
+			data:     append(append(append([]byte{}, block...), block...), 2),
+			expected: pair(pair(leaf[:], leaf[:]), pair(tail[:], zero)),
+		},
+		{
+			name:     "004 one block padded to piece width",
+			data:     []byte{2},
+			width:    4,
+			expected: pair(pair(tail[:], zero), pair(zero, zero)),
+		},
+	}
+	for _, testCase := range cases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root := MerkleRoot(testCase.data, testCase.width)
+			if !bytes.Equal(root, testCase.expected) {
+				t.Fatalf("Unexpected root:\nGot: %x\nExpect: %x", root, testCase.expected)
+			}
+		})
+	}
+}
+
+func TestHasher_VerifyV1(t *testing.T) {
+	dir := t.TempDir()
+	first := bytes.Repeat([]byte{'a'}, 10)
+	second := bytes.Repeat([]byte{'b'}, 7)
+	if err := os.WriteFile(filepath.Join(dir, "first"), first, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "second"), second, 0644); err != nil {
+		t.Fatal(err)
+	}
+	payload := append(append(append([]byte{}, first...), make([]byte, 3)...), second...)
+	var pieces []byte
+	for offset := 0; offset < len(payload); offset += 4 {
+		end := offset + 4
+		if end > len(payload) {
+			end = len(payload)
+		}
+		sum := sha1.Sum(payload[offset:end])
+		pieces = append(pieces, sum[:]...)
+	}
+	// corrupt fourth piece
+	pieces[3*sha1.Size] ^= 0xff
+
+	files := []File{
+		{Path: filepath.Join(dir, "first"), Length: 10},
+		{Length: 3, Padding: true},
+		{Path: filepath.Join(dir, "second"), Length: 7},
+		{Path: filepath.Join(dir, "not_exists"), Length: 4},
+	}
+	pieces = append(pieces, make([]byte, sha1.Size)...)
+
+	hasher := NewHasher(2)
+	defer hasher.Close()
+	results := hasher.VerifyV1(files, 4, pieces)
+	expected := []bool{true, true, true, false, true, false}
+	if len(results) != len(expected) {
+		t.Fatalf("Unexpected pieces count. Got %v, expect %v", len(results), len(expected))
+	}
+	for i := range expected {
+		if results[i] != expected[i] {
+			t.Fatalf("Unexpected result for piece %v.\nGot: %v\nExpect: %v", i, results, expected)
+		}
+	}
+}